@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -55,6 +58,188 @@ func main() {
 	}
 	log.Printf("Tombstone: %s\n", ts.Path())
 
+	var tsOpts []tombstone.Option
+
+	ts.PodGeneration = os.Getenv("KUBEXIT_POD_GENERATION")
+	if ts.PodGeneration == "" {
+		log.Println("Pod Generation: N/A")
+	} else {
+		log.Printf("Pod Generation: %s\n", ts.PodGeneration)
+		tsOpts = append(tsOpts, tombstone.WithPodGeneration(ts.PodGeneration))
+	}
+
+	ts.OwnerRef = os.Getenv("KUBEXIT_OWNER_REF")
+	if ts.OwnerRef == "" {
+		log.Println("Owner Ref: N/A")
+	} else {
+		log.Printf("Owner Ref: %s\n", ts.OwnerRef)
+	}
+
+	ts.Image = os.Getenv("KUBEXIT_IMAGE")
+	if ts.Image == "" {
+		log.Println("Image: N/A")
+	} else {
+		log.Printf("Image: %s\n", ts.Image)
+	}
+
+	ts.ImageDigest = os.Getenv("KUBEXIT_IMAGE_DIGEST")
+	if ts.ImageDigest == "" {
+		log.Println("Image Digest: N/A")
+	} else {
+		log.Printf("Image Digest: %s\n", ts.ImageDigest)
+	}
+
+	ts.PodInstanceID = os.Getenv("KUBEXIT_POD_INSTANCE_ID")
+	if ts.PodInstanceID == "" {
+		log.Println("Pod Instance ID: N/A")
+	} else {
+		log.Printf("Pod Instance ID: %s\n", ts.PodInstanceID)
+	}
+
+	ts.TraceID = os.Getenv("KUBEXIT_TRACE_ID")
+	if ts.TraceID == "" {
+		log.Println("Trace ID: N/A")
+	} else {
+		log.Printf("Trace ID: %s\n", ts.TraceID)
+	}
+
+	ts.CorrelationID = os.Getenv("KUBEXIT_CORRELATION_ID")
+	if ts.CorrelationID == "" {
+		log.Println("Correlation ID: N/A")
+	} else {
+		log.Printf("Correlation ID: %s\n", ts.CorrelationID)
+	}
+
+	nodeName := os.Getenv("KUBEXIT_NODE_NAME")
+	if nodeName == "" {
+		log.Println("Node Name: N/A")
+	} else {
+		ts.NodeInfo = &tombstone.NodeInfo{Name: nodeName}
+		log.Printf("Node Name: %s\n", nodeName)
+	}
+
+	successCodesStr := os.Getenv("KUBEXIT_SUCCESS_CODES")
+	if successCodesStr == "" {
+		log.Println("Success Codes: 0")
+	} else {
+		for _, codeStr := range strings.Split(successCodesStr, ",") {
+			code, err := strconv.Atoi(strings.TrimSpace(codeStr))
+			if err != nil {
+				log.Printf("Error: failed to parse success codes: %v\n", err)
+				os.Exit(2)
+			}
+			ts.SuccessCodes = append(ts.SuccessCodes, code)
+		}
+		log.Printf("Success Codes: 0,%s\n", successCodesStr)
+	}
+
+	cascadePolicyStr := os.Getenv("KUBEXIT_CASCADE_POLICY")
+	if cascadePolicyStr != "" {
+		ts.CascadePolicy, err = tombstone.ParseCascadePolicy(cascadePolicyStr)
+		if err != nil {
+			log.Printf("Error: failed to parse cascade policy: %v\n", err)
+			os.Exit(2)
+		}
+		log.Printf("Cascade Policy: %s\n", ts.CascadePolicy)
+	} else {
+		log.Println("Cascade Policy: always")
+	}
+
+	timeFormatStr := os.Getenv("KUBEXIT_TIME_FORMAT")
+	if timeFormatStr != "" {
+		ts.TimeFormat, err = tombstone.ParseTimeFormat(timeFormatStr)
+		if err != nil {
+			log.Printf("Error: failed to parse time format: %v\n", err)
+			os.Exit(2)
+		}
+		log.Printf("Time Format: %s\n", timeFormatStr)
+	} else {
+		log.Println("Time Format: rfc3339")
+	}
+
+	historyMaxRecordsStr := os.Getenv("KUBEXIT_HISTORY_MAX_RECORDS")
+	historyMaxBytesStr := os.Getenv("KUBEXIT_HISTORY_MAX_BYTES")
+	if historyMaxRecordsStr == "" && historyMaxBytesStr == "" {
+		log.Println("History: N/A")
+	} else {
+		ts.History = &tombstone.HistoryLog{}
+		if historyMaxRecordsStr != "" {
+			ts.History.MaxRecords, err = strconv.Atoi(historyMaxRecordsStr)
+			if err != nil {
+				log.Printf("Error: failed to parse history max records: %v\n", err)
+				os.Exit(2)
+			}
+		}
+		if historyMaxBytesStr != "" {
+			ts.History.MaxBytes, err = strconv.ParseInt(historyMaxBytesStr, 10, 64)
+			if err != nil {
+				log.Printf("Error: failed to parse history max bytes: %v\n", err)
+				os.Exit(2)
+			}
+		}
+		log.Printf("History: max records: %s, max bytes: %s\n", historyMaxRecordsStr, historyMaxBytesStr)
+	}
+
+	exitCodeHistoryStr := os.Getenv("KUBEXIT_EXIT_CODE_HISTORY")
+	if exitCodeHistoryStr != "" {
+		ts.MaxExitCodeHistory, err = strconv.Atoi(exitCodeHistoryStr)
+		if err != nil {
+			log.Printf("Error: failed to parse exit code history: %v\n", err)
+			os.Exit(2)
+		}
+		log.Printf("Exit Code History: %d\n", ts.MaxExitCodeHistory)
+	} else {
+		log.Println("Exit Code History: N/A")
+	}
+
+	mirrorStr := os.Getenv("KUBEXIT_MIRROR_STDOUT")
+	if mirrorStr != "" {
+		mirror, err := strconv.ParseBool(mirrorStr)
+		if err != nil {
+			log.Printf("Error: failed to parse mirror stdout: %v\n", err)
+			os.Exit(2)
+		}
+		if mirror {
+			ts.Mirror = os.Stdout
+		}
+	}
+	log.Printf("Mirror Stdout: %v\n", ts.Mirror != nil)
+
+	noSpacePolicyStr := os.Getenv("KUBEXIT_NO_SPACE_POLICY")
+	if noSpacePolicyStr != "" {
+		ts.OnNoSpace, err = tombstone.ParseNoSpacePolicy(noSpacePolicyStr)
+		if err != nil {
+			log.Printf("Error: failed to parse no space policy: %v\n", err)
+			os.Exit(2)
+		}
+		log.Printf("No Space Policy: %s\n", noSpacePolicyStr)
+	} else {
+		log.Println("No Space Policy: fail")
+	}
+
+	encryptionKeyStr := os.Getenv("KUBEXIT_ENCRYPTION_KEY")
+	if encryptionKeyStr == "" {
+		log.Println("Encryption Key: N/A")
+	} else {
+		secret, err := base64.StdEncoding.DecodeString(encryptionKeyStr)
+		if err != nil {
+			log.Printf("Error: failed to decode encryption key: %v\n", err)
+			os.Exit(2)
+		}
+		keyID := os.Getenv("KUBEXIT_ENCRYPTION_KEY_ID")
+		if keyID == "" {
+			keyID = "default"
+		}
+		key, err := tombstone.NewKey(keyID, secret)
+		if err != nil {
+			log.Printf("Error: invalid encryption key: %v\n", err)
+			os.Exit(2)
+		}
+		ts.Key = key
+		tsOpts = append(tsOpts, tombstone.WithKeys(key))
+		log.Printf("Encryption Key: %s\n", key.ID)
+	}
+
 	birthDepsStr := os.Getenv("KUBEXIT_BIRTH_DEPS")
 	var birthDeps []string
 	if birthDepsStr == "" {
@@ -73,6 +258,10 @@ func main() {
 		log.Printf("Death Deps: %s\n", strings.Join(deathDeps, ","))
 	}
 
+	// recorded at birth
+	ts.BirthDeps = birthDeps
+	ts.DeathDeps = deathDeps
+
 	birthTimeout := 30 * time.Second
 	birthTimeoutStr := os.Getenv("KUBEXIT_BIRTH_TIMEOUT")
 	if birthTimeoutStr != "" {
@@ -95,6 +284,41 @@ func main() {
 	}
 	log.Printf("Grace Period: %s\n", gracePeriod)
 
+	var heartbeatInterval time.Duration
+	heartbeatIntervalStr := os.Getenv("KUBEXIT_HEARTBEAT_INTERVAL")
+	if heartbeatIntervalStr == "" {
+		log.Println("Heartbeat Interval: N/A")
+	} else {
+		heartbeatInterval, err = time.ParseDuration(heartbeatIntervalStr)
+		if err != nil {
+			log.Printf("Error: failed to parse heartbeat interval: %v\n", err)
+			os.Exit(2)
+		}
+		log.Printf("Heartbeat Interval: %s\n", heartbeatInterval)
+	}
+
+	minHeartbeatIntervalStr := os.Getenv("KUBEXIT_MIN_HEARTBEAT_INTERVAL")
+	if minHeartbeatIntervalStr == "" {
+		log.Println("Min Heartbeat Interval: N/A")
+	} else {
+		ts.MinHeartbeatInterval, err = time.ParseDuration(minHeartbeatIntervalStr)
+		if err != nil {
+			log.Printf("Error: failed to parse min heartbeat interval: %v\n", err)
+			os.Exit(2)
+		}
+		log.Printf("Min Heartbeat Interval: %s\n", ts.MinHeartbeatInterval)
+	}
+
+	heartbeatSidecarStr := os.Getenv("KUBEXIT_HEARTBEAT_SIDECAR")
+	if heartbeatSidecarStr != "" {
+		ts.HeartbeatSidecar, err = strconv.ParseBool(heartbeatSidecarStr)
+		if err != nil {
+			log.Printf("Error: failed to parse heartbeat sidecar: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	log.Printf("Heartbeat Sidecar: %v\n", ts.HeartbeatSidecar)
+
 	podName := os.Getenv("KUBEXIT_POD_NAME")
 	if podName == "" {
 		if len(birthDeps) > 0 {
@@ -117,6 +341,21 @@ func main() {
 		log.Printf("Namespace: %s\n", namespace)
 	}
 
+	var recordRestartReason bool
+	recordRestartReasonStr := os.Getenv("KUBEXIT_RECORD_RESTART_REASON")
+	if recordRestartReasonStr != "" {
+		recordRestartReason, err = strconv.ParseBool(recordRestartReasonStr)
+		if err != nil {
+			log.Printf("Error: failed to parse record restart reason: %v\n", err)
+			os.Exit(2)
+		}
+		if recordRestartReason && (podName == "" || namespace == "") {
+			log.Println("Error: missing env var: KUBEXIT_POD_NAME and KUBEXIT_NAMESPACE are required to record restart reason")
+			os.Exit(2)
+		}
+	}
+	log.Printf("Record Restart Reason: %v\n", recordRestartReason)
+
 	child := supervisor.New(args[0], args[1:]...)
 
 	// watch for death deps early, so they can interrupt waiting for birth deps
@@ -126,11 +365,17 @@ func main() {
 		defer stopGraveyardWatcher()
 
 		log.Println("Watching graveyard...")
-		err = tombstone.Watch(ctx, graveyard, onDeathOfAny(deathDeps, func() {
+		err = tombstone.Watch(ctx, graveyard, onDeathOfAny(deathDeps, ts.PodGeneration, tsOpts, func() {
 			stopGraveyardWatcher()
+			// warn dependents before the child actually dies
+			// Skipped if not born.
+			err := ts.RecordTerminating()
+			if err != nil {
+				log.Printf("Error: %v\n", err)
+			}
 			// trigger graceful shutdown
 			// Skipped if not started.
-			err := child.ShutdownWithTimeout(gracePeriod)
+			err = child.ShutdownWithTimeout(gracePeriod)
 			// ShutdownWithTimeout doesn't block until timeout
 			if err != nil {
 				log.Printf("Error: failed to shutdown: %v\n", err)
@@ -142,7 +387,10 @@ func main() {
 	}
 
 	if len(birthDeps) > 0 {
+		start := time.Now()
 		err = waitForBirthDeps(birthDeps, namespace, podName, birthTimeout)
+		// recorded at birth, even if waiting failed
+		ts.RecordBirthWait(time.Since(start))
 		if err != nil {
 			fatalf(child, ts, "Error: %v\n", err)
 		}
@@ -150,17 +398,56 @@ func main() {
 
 	err = child.Start()
 	if err != nil {
-		fatalf(child, ts, "Error: %v\n", err)
+		code := supervisor.StartFailureExitCode(err)
+		log.Printf("Error: %v\n", err)
+		// Record the failure, so that dependents aren't left waiting.
+		err = ts.RecordNeverBorn(code, err)
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+		}
+		os.Exit(code)
 	}
 
+	ts.LoadNodeInfo()
+	ts.LoadUserInfo()
 	err = ts.RecordBirth()
 	if err != nil {
 		fatalf(child, ts, "Error: %v\n", err)
 	}
 
-	code := waitForChildExit(child)
+	if os.Getenv("NOTIFY_SOCKET") != "" {
+		// kubexit has no readiness checks, so under systemd, born is ready
+		err = ts.RecordReady()
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+		}
+	}
+
+	recordTerminatingOnSignal(ts, syscall.SIGTERM)
+
+	stopHeartbeat := func() {}
+	if heartbeatInterval > 0 {
+		stopHeartbeat = heartbeat(ts, heartbeatInterval)
+	}
+
+	restartReason := func() string { return "" }
+	if recordRestartReason {
+		restartReason = lookupRestartReason(namespace, podName, name)
+	}
 
-	err = ts.RecordDeath(code)
+	code, exitErr := waitForChildExit(child)
+	stopHeartbeat()
+
+	// best effort, without blocking
+	ts.RestartReason = restartReason()
+
+	// best effort
+	err = ts.LoadResourceUsage(tombstone.DefaultCgroupRoot)
+	if err != nil {
+		log.Printf("Error: failed to load resource usage: %v\n", err)
+	}
+
+	err = ts.RecordDeathWithError(code, exitErr)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -226,8 +513,101 @@ func withCancelOnSignal(ctx context.Context, signals ...os.Signal) context.Conte
 	return ctx
 }
 
-// wait for the child to exit and return the exit code
-func waitForChildExit(child *supervisor.Supervisor) int {
+// recordTerminatingOnSignal records that the child is terminating when one of
+// the specified signals is recieved. The signals are still propegated to the
+// child by the supervisor.
+func recordTerminatingOnSignal(ts *tombstone.Tombstone, signals ...os.Signal) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+
+	go func() {
+		_, ok := <-sigCh
+		if !ok {
+			return
+		}
+		err := ts.RecordTerminating()
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+		}
+	}()
+}
+
+// lookupRestartReason gets the reason the previous instance of the container
+// terminated from the Kubernetes API, in the background. The returned
+// function returns the reason, or empty if not known (yet), without blocking.
+func lookupRestartReason(namespace, podName, containerName string) func() string {
+	reasonCh := make(chan string, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		client, err := kubernetes.NewInClusterClient()
+		if err != nil {
+			log.Printf("Error: failed to lookup restart reason: %v\n", err)
+			return
+		}
+		reason, err := kubernetes.RestartReason(ctx, client, namespace, podName, containerName)
+		if err != nil {
+			log.Printf("Error: failed to lookup restart reason: %v\n", err)
+			return
+		}
+		if reason != "" {
+			log.Printf("Restart Reason: %s\n", reason)
+		}
+		reasonCh <- reason
+	}()
+
+	var reason string
+	return func() string {
+		select {
+		case reason = <-reasonCh:
+		default:
+		}
+		return reason
+	}
+}
+
+// heartbeat updates the tombstone heartbeat on an interval, until stopped.
+// Stop blocks until the last heartbeat is done, and then writes it, if
+// throttled by the MinHeartbeatInterval.
+func heartbeat(ts *tombstone.Tombstone, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				err := ts.Heartbeat()
+				if err != nil {
+					log.Printf("Error: %v\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+		<-stopped
+
+		err := ts.FlushHeartbeat()
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+		}
+	}
+}
+
+// wait for the child to exit and return the exit code and error, if any
+func waitForChildExit(child *supervisor.Supervisor) (int, error) {
+	if os.Getpid() == 1 {
+		return waitForChildExitAndReap(child)
+	}
+
 	var code int
 	err := child.Wait()
 	if err != nil {
@@ -241,7 +621,24 @@ func waitForChildExit(child *supervisor.Supervisor) int {
 		code = 0
 		log.Println("Child Exited(0)")
 	}
-	return code
+	return code, err
+}
+
+// waitForChildExitAndReap is waitForChildExit for PID 1, which must also
+// reap orphaned processes, so they don't become zombies.
+func waitForChildExitAndReap(child *supervisor.Supervisor) (int, error) {
+	status, err := child.WaitAndReap(syscall.Wait4)
+	if err != nil {
+		var exitErr *supervisor.ExitError
+		code := -1
+		if errors.As(err, &exitErr) {
+			code = exitErr.Status.ExitStatus()
+		}
+		log.Printf("Child Exited(%d): %v\n", code, err)
+		return code, err
+	}
+	log.Println("Child Exited(0)")
+	return status.ExitStatus(), nil
 }
 
 // fatalf is for terminal errors.
@@ -249,7 +646,19 @@ func waitForChildExit(child *supervisor.Supervisor) int {
 func fatalf(child *supervisor.Supervisor, ts *tombstone.Tombstone, msg string, args ...interface{}) {
 	log.Printf(msg, args...)
 
-	// Skipped if not started.
+	// Attempt to record death, if possible.
+	// Another process may be waiting for it.
+	cause := fmt.Errorf(strings.TrimSpace(strings.TrimPrefix(msg, "Error: ")), args...)
+
+	if !child.Started() {
+		// no child exit code to record
+		err := ts.RecordWrapperFailureBeforeStart(1, cause)
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
 	err := child.ShutdownNow()
 	if err != nil {
 		log.Printf("Error: failed to shutdown child process: %v", err)
@@ -258,11 +667,9 @@ func fatalf(child *supervisor.Supervisor, ts *tombstone.Tombstone, msg string, a
 
 	// Wait for shutdown...
 	//TODO: timout in case the process is zombie?
-	code := waitForChildExit(child)
+	code, _ := waitForChildExit(child)
 
-	// Attempt to record death, if possible.
-	// Another process may be waiting for it.
-	err = ts.RecordDeath(code)
+	err = ts.RecordWrapperFailure(1, code, cause)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -314,7 +721,7 @@ func onReadyOfAll(birthDeps []string, callback func()) kubernetes.EventHandler {
 
 // onDeathOfAny returns an EventHandler that executes the callback when any of
 // the deathDeps processes have died.
-func onDeathOfAny(deathDeps []string, callback func()) tombstone.EventHandler {
+func onDeathOfAny(deathDeps []string, generation string, tsOpts []tombstone.Option, callback func()) tombstone.EventHandler {
 	deathDepSet := map[string]struct{}{}
 	for _, depName := range deathDeps {
 		deathDepSet[depName] = struct{}{}
@@ -335,7 +742,7 @@ func onDeathOfAny(deathDeps []string, callback func()) tombstone.EventHandler {
 		}
 
 		log.Printf("Reading tombstone: %s\n", name)
-		ts, err := tombstone.Read(graveyard, name)
+		ts, err := tombstone.Read(graveyard, name, tsOpts...)
 		if err != nil {
 			log.Printf("Error: failed to read tombstone: %v\n", err)
 			return
@@ -345,6 +752,14 @@ func onDeathOfAny(deathDeps []string, callback func()) tombstone.EventHandler {
 			// still alive
 			return
 		}
+		if !ts.ShouldCascade() {
+			log.Printf("Ignoring death with cascade policy %s: %s\n", ts.CascadePolicy, name)
+			return
+		}
+		if generation != "" && ts.PodGeneration != generation {
+			log.Printf("Ignoring death from other pod generation: %s (%s)\n", name, ts.PodGeneration)
+			return
+		}
 		log.Printf("New death: %s\n", name)
 		log.Printf("Tombstone(%s): %s\n", name, ts)
 
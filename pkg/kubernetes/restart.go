@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// NewInClusterClient creates a kubernetes client using the pod service
+// account.
+func NewInClusterClient() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure kubernetes client: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %v", err)
+	}
+	return clientset, nil
+}
+
+// RestartReason gets the pod and returns the reason the previous instance of
+// the named container terminated (ex: OOMKilled, Error), from its
+// lastState.terminated.reason. Returns empty if the container hasn't been
+// restarted.
+func RestartReason(ctx context.Context, client kubernetes.Interface, namespace, podName, containerName string) (string, error) {
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod: %v", err)
+	}
+	return LastTerminationReason(pod, containerName), nil
+}
+
+// LastTerminationReason returns the reason the previous instance of the
+// named container terminated, from the pod status, or empty if unknown.
+func LastTerminationReason(pod *corev1.Pod, containerName string) string {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if status.Name != containerName {
+				continue
+			}
+			if status.LastTerminationState.Terminated == nil {
+				return ""
+			}
+			return status.LastTerminationState.Terminated.Reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func terminated(reason string) corev1.ContainerState {
+	return corev1.ContainerState{
+		Terminated: &corev1.ContainerStateTerminated{Reason: reason},
+	}
+}
+
+func testPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "init", LastTerminationState: terminated("Error")},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", LastTerminationState: terminated("OOMKilled")},
+				{Name: "sidecar"},
+			},
+		},
+	}
+}
+
+func TestLastTerminationReason(t *testing.T) {
+	tests := []struct {
+		container string
+		want      string
+	}{
+		{container: "app", want: "OOMKilled"},
+		{container: "init", want: "Error"},
+		// not restarted
+		{container: "sidecar", want: ""},
+		{container: "missing", want: ""},
+	}
+	pod := testPod()
+	for _, tt := range tests {
+		t.Run(tt.container, func(t *testing.T) {
+			if got := LastTerminationReason(pod, tt.container); got != tt.want {
+				t.Fatalf("expected reason %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRestartReason(t *testing.T) {
+	client := fake.NewSimpleClientset(testPod())
+
+	reason, err := RestartReason(context.Background(), client, "default", "pod", "app")
+	if err != nil {
+		t.Fatalf("failed to get restart reason: %v", err)
+	}
+	if reason != "OOMKilled" {
+		t.Fatalf("expected OOMKilled, got: %q", reason)
+	}
+
+	if _, err := RestartReason(context.Background(), client, "default", "missing", "app"); err == nil {
+		t.Fatalf("expected missing pod to fail")
+	}
+}
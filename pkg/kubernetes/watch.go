@@ -2,7 +2,6 @@ package kubernetes
 
 import (
 	"context"
-	"fmt"
 	"log"
 
 	corev1 "k8s.io/api/core/v1"
@@ -11,8 +10,6 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	watchtools "k8s.io/client-go/tools/watch"
 )
@@ -22,13 +19,9 @@ type EventHandler func(watch.Event)
 // Watch a pod and call the eventHandler (asyncronously) when an
 // event happens. When the supplied context is canceled, watching will stop.
 func WatchPod(ctx context.Context, namespace, podName string, eventHandler EventHandler) error {
-	config, err := rest.InClusterConfig()
+	clientset, err := NewInClusterClient()
 	if err != nil {
-		return fmt.Errorf("failed to configure kubernetes client: %v", err)
-	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
+		return err
 	}
 
 	// Watch doesn't take name matches, only selectors. So select on name.
@@ -0,0 +1,198 @@
+// Package remote streams graveyard changes to remote observers over gRPC,
+// so they don't need to mount the graveyard volume.
+//
+// Messages are encoded as JSON, using a registered gRPC codec, so no
+// generated protobuf code is required. The gRPC dependency is isolated to
+// this package.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/karlkfi/kubexit/pkg/tombstone"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	// ServiceName is the full gRPC service name.
+	ServiceName = "kubexit.Graveyard"
+	// CodecName is the gRPC content-subtype used for all messages.
+	CodecName = "json"
+
+	subscribeMethod = "/" + ServiceName + "/Subscribe"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec is a gRPC codec that encodes messages as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return CodecName
+}
+
+// SubscribeRequest starts a subscription.
+type SubscribeRequest struct {
+	// Names filters events to the named tombstones. Empty means all.
+	Names []string `json:",omitempty"`
+}
+
+// Event is a change to a tombstone in the graveyard.
+type Event struct {
+	// Op is the fsnotify operation (ex: CREATE, WRITE, REMOVE).
+	Op   string
+	Name string
+	// Tombstone is the current content, unless removed or unreadable.
+	Tombstone *tombstone.Tombstone `json:",omitempty"`
+}
+
+// Server streams the changes of a local graveyard.
+type Server struct {
+	graveyard string
+	opts      []tombstone.Option
+}
+
+// NewServer returns a server for the graveyard.
+// The options are used to watch and read tombstones.
+func NewServer(graveyard string, opts ...tombstone.Option) *Server {
+	return &Server{
+		graveyard: graveyard,
+		opts:      opts,
+	}
+}
+
+// Register the server with a gRPC server.
+func Register(s *grpc.Server, srv *Server) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       subscribeHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+func subscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := &SubscribeRequest{}
+	err := stream.RecvMsg(req)
+	if err != nil {
+		return err
+	}
+	return srv.(*Server).subscribe(req, stream)
+}
+
+func (s *Server) subscribe(req *SubscribeRequest, stream grpc.ServerStream) error {
+	ctx := stream.Context()
+
+	filter := map[string]struct{}{}
+	for _, name := range req.Names {
+		filter[name] = struct{}{}
+	}
+
+	sub, err := tombstone.Subscribe(ctx, s.graveyard, tombstone.SubscribeOptions{
+		Overflow: tombstone.Block,
+	}, s.opts...)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %v", err)
+	}
+
+	for event := range sub.Events() {
+		name := filepath.Base(event.Name)
+		if len(filter) > 0 {
+			if _, ok := filter[name]; !ok {
+				continue
+			}
+		}
+
+		msg := &Event{
+			Op:   event.Op.String(),
+			Name: name,
+		}
+		if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+			ts, err := tombstone.Read(s.graveyard, name, s.opts...)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Remote Subscribe: failed to read tombstone: %v\n", err)
+			}
+			msg.Tombstone = ts
+		}
+
+		err = stream.SendMsg(msg)
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// Client subscribes to a remote graveyard.
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// NewClient returns a client using the connection.
+func NewClient(conn *grpc.ClientConn) *Client {
+	return &Client{
+		conn: conn,
+	}
+}
+
+// EventStream receives events from a subscription.
+type EventStream struct {
+	stream grpc.ClientStream
+}
+
+// Recv blocks until the next event is received.
+// Returns io.EOF when the server ends the stream.
+func (s *EventStream) Recv() (*Event, error) {
+	event := &Event{}
+	err := s.stream.RecvMsg(event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Subscribe to graveyard changes. The stream ends when the context is
+// canceled.
+func (c *Client) Subscribe(ctx context.Context, req *SubscribeRequest) (*EventStream, error) {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], subscribeMethod,
+		grpc.CallContentSubtype(CodecName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %v", err)
+	}
+	err = stream.SendMsg(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return nil, fmt.Errorf("failed to close send: %v", err)
+	}
+	return &EventStream{
+		stream: stream,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package remote
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/karlkfi/kubexit/pkg/tombstone"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts a server for the graveyard on an in-memory listener and
+// returns a client connected to it.
+func dial(t *testing.T, graveyard string) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	Register(server, NewServer(graveyard))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+func TestSubscribe(t *testing.T) {
+	graveyard, err := ioutil.TempDir("", "graveyard")
+	if err != nil {
+		t.Fatalf("failed to create graveyard: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(graveyard) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := dial(t, graveyard)
+	stream, err := client.Subscribe(ctx, &SubscribeRequest{Names: []string{"app"}})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	// keep writing until received, because the server subscribes async
+	go func() {
+		other := &tombstone.Tombstone{Graveyard: graveyard, Name: "other"}
+		app := &tombstone.Tombstone{Graveyard: graveyard, Name: "app"}
+		for ctx.Err() == nil {
+			other.RecordBirth()
+			app.RecordBirth()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+		if event.Name != "app" {
+			t.Fatalf("expected only app events, got: %s", event.Name)
+		}
+		if event.Tombstone != nil && event.Tombstone.Born != nil {
+			return
+		}
+	}
+}
+
+func TestSubscribeCanceled(t *testing.T) {
+	graveyard, err := ioutil.TempDir("", "graveyard")
+	if err != nil {
+		t.Fatalf("failed to create graveyard: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(graveyard) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := dial(t, graveyard)
+	stream, err := client.Subscribe(ctx, &SubscribeRequest{})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	cancel()
+	if _, err := stream.Recv(); err == nil {
+		t.Fatalf("expected canceled stream to fail")
+	}
+}
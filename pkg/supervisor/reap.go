@@ -0,0 +1,87 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+)
+
+// WaitFunc waits for a child process to change state, like syscall.Wait4.
+// It can be replaced to inject wait results.
+type WaitFunc func(pid int, status *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error)
+
+// ErrNotStarted is returned by WaitAndReap when the child was never started.
+var ErrNotStarted = errors.New("child process not started")
+
+// ExitError is returned by WaitAndReap when the child did not exit zero.
+type ExitError struct {
+	Status syscall.WaitStatus
+}
+
+func (e *ExitError) Error() string {
+	if e.Status.Signaled() {
+		return fmt.Sprintf("signal: %v", e.Status.Signal())
+	}
+	return fmt.Sprintf("exit status %d", e.Status.ExitStatus())
+}
+
+// ReapUntil waits for any child process to exit, until the child with the
+// pid exits, and returns its wait status. Other children (ex: orphans
+// re-parented to PID 1) are reaped and ignored, so they don't become zombies.
+func ReapUntil(pid int, wait WaitFunc) (syscall.WaitStatus, error) {
+	for {
+		var status syscall.WaitStatus
+		wpid, err := wait(-1, &status, 0, nil)
+		if err != nil {
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return status, fmt.Errorf("failed to wait for child process: %v", err)
+		}
+		if wpid != pid {
+			log.Printf("Reaped orphan process: %d\n", wpid)
+			continue
+		}
+		if !status.Exited() && !status.Signaled() {
+			// stopped or continued
+			continue
+		}
+		return status, nil
+	}
+}
+
+// WaitAndReap is like Wait, but also reaps orphaned processes, which is
+// required when running as PID 1. It must be used instead of Wait, not as
+// well, because it waits for any child.
+// An ExitError is returned if the child did not exit zero, or ErrNotStarted
+// if it was never started.
+func (s *Supervisor) WaitAndReap(wait WaitFunc) (syscall.WaitStatus, error) {
+	if !s.Started() {
+		return 0, ErrNotStarted
+	}
+	defer func() {
+		signal.Reset()
+		if s.sigCh != nil {
+			close(s.sigCh)
+		}
+		if s.shutdownTimer != nil {
+			s.shutdownTimer.Stop()
+		}
+	}()
+	log.Println("Waiting for child process to exit (reaping orphans)...")
+	status, err := ReapUntil(s.cmd.Process.Pid, wait)
+
+	s.startStopLock.Lock()
+	s.reaped = true
+	s.startStopLock.Unlock()
+
+	if err != nil {
+		return status, err
+	}
+	if !status.Exited() || status.ExitStatus() != 0 {
+		return status, &ExitError{Status: status}
+	}
+	return status, nil
+}
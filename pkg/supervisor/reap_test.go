@@ -0,0 +1,119 @@
+package supervisor
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+// waitResult is a result returned by a fake WaitFunc.
+type waitResult struct {
+	pid    int
+	status syscall.WaitStatus
+	err    error
+}
+
+// fakeWait returns a WaitFunc that returns the results in order.
+func fakeWait(t *testing.T, results ...waitResult) WaitFunc {
+	return func(pid int, status *syscall.WaitStatus, options int, rusage *syscall.Rusage) (int, error) {
+		if pid != -1 {
+			t.Errorf("expected to wait for any child, got pid: %d", pid)
+		}
+		if len(results) == 0 {
+			t.Fatalf("unexpected wait")
+		}
+		result := results[0]
+		results = results[1:]
+		*status = result.status
+		return result.pid, result.err
+	}
+}
+
+// Linux wait status encodings
+func exited(code int) syscall.WaitStatus {
+	return syscall.WaitStatus(code << 8)
+}
+
+func signaled(sig syscall.Signal) syscall.WaitStatus {
+	return syscall.WaitStatus(sig)
+}
+
+func stopped(sig syscall.Signal) syscall.WaitStatus {
+	return syscall.WaitStatus(0x7f | int(sig)<<8)
+}
+
+func TestReapUntil(t *testing.T) {
+	wait := fakeWait(t,
+		// orphans re-parented to PID 1
+		waitResult{pid: 101, status: exited(0)},
+		waitResult{pid: 102, status: signaled(syscall.SIGKILL)},
+		waitResult{err: syscall.EINTR},
+		// the child, stopped and continued, before exiting
+		waitResult{pid: 42, status: stopped(syscall.SIGSTOP)},
+		waitResult{pid: 42, status: exited(3)},
+	)
+
+	status, err := ReapUntil(42, wait)
+	if err != nil {
+		t.Fatalf("failed to reap: %v", err)
+	}
+	if !status.Exited() || status.ExitStatus() != 3 {
+		t.Fatalf("expected child exit status 3, got: %v", status)
+	}
+}
+
+func TestReapUntilSignaled(t *testing.T) {
+	status, err := ReapUntil(42, fakeWait(t, waitResult{pid: 42, status: signaled(syscall.SIGTERM)}))
+	if err != nil {
+		t.Fatalf("failed to reap: %v", err)
+	}
+	if !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Fatalf("expected child to be signaled, got: %v", status)
+	}
+}
+
+func TestReapUntilError(t *testing.T) {
+	_, err := ReapUntil(42, fakeWait(t, waitResult{err: syscall.ECHILD}))
+	if err == nil {
+		t.Fatalf("expected wait error")
+	}
+}
+
+func TestWaitAndReapNotStarted(t *testing.T) {
+	child := New("true")
+	_, err := child.WaitAndReap(fakeWait(t))
+	if !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("expected ErrNotStarted, got: %v", err)
+	}
+}
+
+func TestWaitAndReap(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{name: "success", script: "exit 0"},
+		{name: "failure", script: "exit 3", wantErr: "exit status 3"},
+		{name: "signaled", script: "kill -TERM $$", wantErr: "signal: terminated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			child := New("sh", "-c", tt.script)
+			if err := child.Start(); err != nil {
+				t.Fatalf("failed to start: %v", err)
+			}
+			_, err := child.WaitAndReap(syscall.Wait4)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				return
+			}
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) || err.Error() != tt.wantErr {
+				t.Fatalf("expected ExitError %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
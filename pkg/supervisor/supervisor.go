@@ -19,6 +19,8 @@ type Supervisor struct {
 	sigCh         chan os.Signal
 	startStopLock sync.Mutex
 	shutdownTimer *time.Timer
+	// reaped is true if the child was waited for by WaitAndReap
+	reaped bool
 }
 
 func New(name string, args ...string) *Supervisor {
@@ -41,7 +43,8 @@ func (s *Supervisor) Start() error {
 
 	log.Printf("Starting: %s\n", s)
 	if err := s.cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start child process: %v", err)
+		// wrap, so callers can check the cause with StartFailureExitCode
+		return fmt.Errorf("failed to start child process: %w", err)
 	}
 
 	// Propegate all signals to the child process
@@ -136,12 +139,34 @@ func (s *Supervisor) ShutdownWithTimeout(timeout time.Duration) error {
 	return nil
 }
 
+// StartFailureExitCode returns the exit code to use when Start fails,
+// following shell conventions: 127 if the command was not found, 126 if it
+// could not be executed (ex: permission denied), otherwise 1.
+func StartFailureExitCode(err error) int {
+	switch {
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		return 127
+	case errors.Is(err, os.ErrPermission), errors.Is(err, syscall.ENOEXEC):
+		return 126
+	default:
+		return 1
+	}
+}
+
+// Started returns true if the child process was started.
+func (s *Supervisor) Started() bool {
+	s.startStopLock.Lock()
+	defer s.startStopLock.Unlock()
+
+	return s.cmd.Process != nil
+}
+
 func (s *Supervisor) isRunning() bool {
 	// Process set by cmd.Start - means started
 	// https://golang.org/src/os/exec/exec.go?s=11514:11541#L422
 	// ProcessState set by cmd.Wait - means exited
 	// https://golang.org/src/os/exec/exec.go?s=14689:14715#L511
-	return s.cmd.Process != nil && s.cmd.ProcessState == nil
+	return s.cmd.Process != nil && s.cmd.ProcessState == nil && !s.reaped
 }
 
 // String joins the command Path and Args and quotes any with spaces
@@ -0,0 +1,56 @@
+package supervisor
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartFailureExitCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supervisor")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	notExecutable := filepath.Join(dir, "not-executable")
+	if err := ioutil.WriteFile(notExecutable, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	badFormat := filepath.Join(dir, "bad-format")
+	if err := ioutil.WriteFile(badFormat, []byte("not a binary\n"), 0755); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		want    int
+	}{
+		{name: "not found in path", command: "kubexit-no-such-command", want: 127},
+		{name: "not found", command: filepath.Join(dir, "missing"), want: 127},
+		{name: "permission denied", command: notExecutable, want: 126},
+		{name: "exec format error", command: badFormat, want: 126},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			child := New(tt.command)
+			err := child.Start()
+			if err == nil {
+				t.Fatalf("expected start to fail")
+			}
+			if child.Started() {
+				t.Fatalf("expected child not to be started")
+			}
+			if got := StartFailureExitCode(err); got != tt.want {
+				t.Fatalf("expected exit code %d, got %d: %v", tt.want, got, err)
+			}
+		})
+	}
+
+	if got := StartFailureExitCode(errors.New("other")); got != 1 {
+		t.Fatalf("expected exit code 1 for other errors, got: %d", got)
+	}
+}
@@ -0,0 +1,71 @@
+package tombstone
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// benchmarkPrefix prefixes the hidden tombstone written by BenchmarkWrite.
+const benchmarkPrefix = ".benchmark."
+
+// WriteLatencyStats summarizes the latency of tombstone writes.
+type WriteLatencyStats struct {
+	Samples int
+	Min     time.Duration
+	Median  time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+func (s WriteLatencyStats) String() string {
+	return fmt.Sprintf("samples=%d min=%s median=%s p99=%s max=%s",
+		s.Samples, s.Min, s.Median, s.P99, s.Max)
+}
+
+// BenchmarkWrite writes and removes a hidden temporary tombstone in the
+// graveyard samples times and returns the write latency stats, so callers
+// can check that the graveyard volume is fast enough before relying on it.
+// If the graveyard does not exist, it is created.
+func BenchmarkWrite(graveyard string, samples int) (WriteLatencyStats, error) {
+	if samples < 1 {
+		return WriteLatencyStats{}, fmt.Errorf("invalid samples: must be at least 1: %d", samples)
+	}
+
+	ts := &Tombstone{
+		Graveyard: graveyard,
+		Name:      fmt.Sprintf("%s%d", benchmarkPrefix, os.Getpid()),
+	}
+	defer os.Remove(ts.Path())
+
+	latencies := make([]time.Duration, 0, samples)
+	for i := 0; i < samples; i++ {
+		now := time.Now()
+		ts.Born = &now
+
+		start := time.Now()
+		err := ts.Write()
+		latencies = append(latencies, time.Since(start))
+		if err != nil {
+			return WriteLatencyStats{}, err
+		}
+
+		err = os.Remove(ts.Path())
+		if err != nil {
+			return WriteLatencyStats{}, fmt.Errorf("failed to remove benchmark tombstone: %v", err)
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	return WriteLatencyStats{
+		Samples: samples,
+		Min:     latencies[0],
+		Median:  latencies[(samples-1)/2],
+		P99:     latencies[int(math.Ceil(0.99*float64(samples)))-1],
+		Max:     latencies[samples-1],
+	}, nil
+}
@@ -0,0 +1,48 @@
+package tombstone
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestBenchmarkWrite(t *testing.T) {
+	// created, if missing
+	graveyard := filepath.Join(tempGraveyard(t), "graveyard")
+
+	stats, err := BenchmarkWrite(graveyard, 20)
+	if err != nil {
+		t.Fatalf("failed to benchmark: %v", err)
+	}
+	if stats.Samples != 20 {
+		t.Fatalf("expected 20 samples, got: %d", stats.Samples)
+	}
+	if stats.Min <= 0 || stats.Min > stats.Median || stats.Median > stats.P99 || stats.P99 > stats.Max {
+		t.Fatalf("expected ordered latency stats, got: %s", stats)
+	}
+
+	// cleaned up
+	files, err := ioutil.ReadDir(graveyard)
+	if err != nil {
+		t.Fatalf("failed to read graveyard: %v", err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected no files left, got: %d", len(files))
+	}
+}
+
+func TestBenchmarkWriteOneSample(t *testing.T) {
+	stats, err := BenchmarkWrite(tempGraveyard(t), 1)
+	if err != nil {
+		t.Fatalf("failed to benchmark: %v", err)
+	}
+	if stats.Min != stats.Max || stats.Median != stats.Max || stats.P99 != stats.Max {
+		t.Fatalf("expected all stats to be the one sample, got: %s", stats)
+	}
+}
+
+func TestBenchmarkWriteInvalidSamples(t *testing.T) {
+	if _, err := BenchmarkWrite(tempGraveyard(t), 0); err == nil {
+		t.Fatalf("expected invalid samples to fail")
+	}
+}
@@ -0,0 +1,72 @@
+package tombstone
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrBirthDepsTimeout is returned by AwaitBirthDeps when one or more birth
+// deps were not born before the timeout.
+type ErrBirthDepsTimeout struct {
+	Timeout time.Duration
+	// Missing lists the birth deps that were not born, in the order requested.
+	Missing []string
+}
+
+func (e *ErrBirthDepsTimeout) Error() string {
+	return fmt.Sprintf("timed out waiting for birth deps to be born: %s: missing: %s",
+		e.Timeout, strings.Join(e.Missing, ", "))
+}
+
+// AwaitBirthDeps blocks until all of the named birth deps have been born in
+// the graveyard, or the timeout elapses. Tombstones that already record a
+// birth count immediately. A single graveyard watcher is used for all deps.
+// If the graveyard does not exist, it is created, so that it can be watched.
+func AwaitBirthDeps(ctx context.Context, graveyard string, deps []string, timeout time.Duration, opts ...Option) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, missing, err := await(ctx, graveyard, deps, len(deps), newOptions(opts), born)
+	if err == context.DeadlineExceeded {
+		return &ErrBirthDepsTimeout{
+			Timeout: timeout,
+			Missing: missing,
+		}
+	} else if err != nil {
+		return fmt.Errorf("waiting for birth deps to be born: %v", err)
+	}
+	return nil
+}
+
+// RecordBirthWait sets the BirthWaitDuration (ex: how long the supervisor
+// waited for birth deps to be ready), to be written at birth.
+func (t *Tombstone) RecordBirthWait(d time.Duration) {
+	t.BirthWaitDuration = &d
+}
+
+// WaitForBirth blocks until the named tombstone records a birth, or the
+// context is done.
+func WaitForBirth(ctx context.Context, graveyard, name string, opts ...Option) error {
+	_, _, err := await(ctx, graveyard, []string{name}, 1, newOptions(opts), born)
+	if err != nil {
+		return fmt.Errorf("waiting for birth of %s: %v", name, err)
+	}
+	return nil
+}
+
+// WaitForQuorumBorn blocks until at least quorum of the named tombstones
+// record a birth, or the context is done. The names born when the quorum was
+// reached are returned, in the order requested. Already born tombstones count
+// immediately.
+func WaitForQuorumBorn(ctx context.Context, graveyard string, names []string, quorum int, opts ...Option) ([]string, error) {
+	if quorum < 1 || quorum > len(names) {
+		return nil, fmt.Errorf("invalid quorum: must be between 1 and %d: %d", len(names), quorum)
+	}
+	bornNames, _, err := await(ctx, graveyard, names, quorum, newOptions(opts), born)
+	if err != nil {
+		return bornNames, fmt.Errorf("waiting for quorum of %d born: %v", quorum, err)
+	}
+	return bornNames, nil
+}
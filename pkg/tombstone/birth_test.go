@@ -0,0 +1,204 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mustRecordBirth records a birth for the named tombstone or fails the test.
+func mustRecordBirth(t *testing.T, graveyard, name string) *Tombstone {
+	t.Helper()
+	ts := &Tombstone{Graveyard: graveyard, Name: name}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth of %s: %v", name, err)
+	}
+	return ts
+}
+
+// recordBirthLater records a birth for the named tombstone after the delay,
+// in the background. The test waits for it to finish, before the graveyard
+// is removed.
+func recordBirthLater(t *testing.T, graveyard, name string, delay time.Duration) {
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		<-done
+	})
+	go func() {
+		defer close(done)
+		time.Sleep(delay)
+		ts := &Tombstone{Graveyard: graveyard, Name: name}
+		if err := ts.RecordBirth(); err != nil {
+			t.Errorf("failed to record birth of %s: %v", name, err)
+		}
+	}()
+}
+
+func TestAwaitBirthDepsAlreadyBorn(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "a")
+	mustRecordBirth(t, graveyard, "b")
+
+	err := AwaitBirthDeps(context.Background(), graveyard, []string{"a", "b"}, time.Second)
+	if err != nil {
+		t.Fatalf("expected already born deps to count, got: %v", err)
+	}
+}
+
+func TestAwaitBirthDepsBornLater(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "a")
+
+	recordBirthLater(t, graveyard, "b", 50*time.Millisecond)
+
+	err := AwaitBirthDeps(context.Background(), graveyard, []string{"a", "b"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected deps to be born, got: %v", err)
+	}
+}
+
+func TestAwaitBirthDepsTimeout(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "b")
+	// written, but not born
+	if err := (&Tombstone{Graveyard: graveyard, Name: "c"}).Write(); err != nil {
+		t.Fatalf("failed to write tombstone: %v", err)
+	}
+
+	err := AwaitBirthDeps(context.Background(), graveyard, []string{"c", "b", "a"}, 100*time.Millisecond)
+	var timeout *ErrBirthDepsTimeout
+	if !errors.As(err, &timeout) {
+		t.Fatalf("expected ErrBirthDepsTimeout, got: %v", err)
+	}
+	if timeout.Timeout != 100*time.Millisecond {
+		t.Errorf("expected timeout 100ms, got: %s", timeout.Timeout)
+	}
+	if want := []string{"c", "a"}; !reflect.DeepEqual(timeout.Missing, want) {
+		t.Errorf("expected missing %v, in the order requested, got: %v", want, timeout.Missing)
+	}
+}
+
+func TestAwaitBirthDepsCreatesGraveyard(t *testing.T) {
+	graveyard := filepath.Join(tempGraveyard(t), "missing")
+
+	err := AwaitBirthDeps(context.Background(), graveyard, []string{"a"}, 10*time.Millisecond)
+	if err == nil {
+		t.Fatalf("expected timeout before birth")
+	}
+	if _, err := os.Stat(graveyard); err != nil {
+		t.Fatalf("expected graveyard to be created: %v", err)
+	}
+
+	recordBirthLater(t, graveyard, "a", 50*time.Millisecond)
+	err = AwaitBirthDeps(context.Background(), graveyard, []string{"a"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected dep to be born in created graveyard, got: %v", err)
+	}
+}
+
+func TestWaitForBirthPodGeneration(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	stale := &Tombstone{Graveyard: graveyard, Name: "a", PodGeneration: "old"}
+	if err := stale.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := WaitForBirth(ctx, graveyard, "a", WithPodGeneration("new"))
+	if err == nil {
+		t.Fatalf("expected tombstone from other generation to be ignored")
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		current := &Tombstone{Graveyard: graveyard, Name: "a", PodGeneration: "new"}
+		if err := current.RecordBirth(); err != nil {
+			t.Errorf("failed to record birth: %v", err)
+		}
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = WaitForBirth(ctx, graveyard, "a", WithPodGeneration("new"))
+	if err != nil {
+		t.Fatalf("expected birth from current generation, got: %v", err)
+	}
+}
+
+func TestWaitForBirthNoPodGeneration(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "a", PodGeneration: "old"}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := WaitForBirth(ctx, graveyard, "a", WithPodGeneration(""))
+	if err != nil {
+		t.Fatalf("expected empty generation to disable filtering, got: %v", err)
+	}
+}
+
+func TestWaitForQuorumBorn(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "b")
+	recordBirthLater(t, graveyard, "c", 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	names, err := WaitForQuorumBorn(ctx, graveyard, []string{"a", "b", "c"}, 2)
+	if err != nil {
+		t.Fatalf("expected quorum, got: %v", err)
+	}
+	// in the order requested
+	if !reflect.DeepEqual(names, []string{"b", "c"}) {
+		t.Fatalf("expected [b c] born, got: %v", names)
+	}
+}
+
+func TestWaitForQuorumBornTimeout(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	names, err := WaitForQuorumBorn(ctx, graveyard, []string{"a", "b", "c"}, 2)
+	if err == nil {
+		t.Fatalf("expected timeout without quorum")
+	}
+	if !reflect.DeepEqual(names, []string{"a"}) {
+		t.Fatalf("expected [a] born, got: %v", names)
+	}
+}
+
+func TestWaitForQuorumBornInvalid(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	for _, quorum := range []int{0, 4} {
+		_, err := WaitForQuorumBorn(context.Background(), graveyard, []string{"a", "b", "c"}, quorum)
+		if err == nil {
+			t.Fatalf("expected invalid quorum %d to fail", quorum)
+		}
+	}
+}
+
+func TestBirthWaitRoundTrip(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	ts.RecordBirthWait(1500 * time.Millisecond)
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	got := mustRead(t, graveyard, "app")
+	if got.BirthWaitDuration == nil || *got.BirthWaitDuration != 1500*time.Millisecond {
+		t.Fatalf("expected birth wait of 1.5s, got: %v", got.BirthWaitDuration)
+	}
+}
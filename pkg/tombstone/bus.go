@@ -0,0 +1,206 @@
+package tombstone
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Bus watches a graveyard and fans out tombstone changes to any number of
+// subscribers, each with its own buffer and overflow policy. One watcher
+// drives all subscriptions. Existing tombstones are not delivered, only their later
+// changes, and subscribers only receive changes made after subscribing.
+type Bus struct {
+	dropped uint64
+
+	lock   sync.Mutex
+	ctx    context.Context
+	prev   map[string]*Tombstone
+	subs   []*busSubscriber
+	closed bool
+}
+
+// busSubscriber is a buffered channel, the filter for what it receives, and
+// what to do when its buffer is full.
+type busSubscriber struct {
+	events   chan *Tombstone
+	overflow OverflowPolicy
+	match    func(transitions []EventType, ts *Tombstone) bool
+}
+
+// NewBus watches a graveyard and returns a Bus to subscribe to. When the
+// supplied context is canceled, watching will stop and all subscription
+// channels will be closed. Watch options (ex: WithShard) are passed through
+// to Watch.
+func NewBus(ctx context.Context, graveyard string, watchOpts ...Option) (*Bus, error) {
+	config := newOptions(watchOpts)
+
+	b := &Bus{
+		ctx:  ctx,
+		prev: map[string]*Tombstone{},
+	}
+
+	// read before watching, so existing tombstones aren't seen as new
+	tombstones, err := ReadAll(graveyard, withOptions(config))
+	if err != nil {
+		return nil, err
+	}
+	for _, ts := range tombstones {
+		b.prev[ts.Name] = ts
+	}
+
+	err = Watch(ctx, graveyard, readingHandler(graveyard, config, b.publish), withOptions(config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+
+	// read again after watching, so no changes are missed, while holding
+	// the lock, so changes seen by the watcher can't interleave
+	b.lock.Lock()
+	tombstones, err = ReadAll(graveyard, withOptions(config))
+	if err == nil {
+		for _, ts := range tombstones {
+			b.publishLocked(EventWrite, ts.Name, ts)
+		}
+	}
+	b.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.closed = true
+		for _, sub := range b.subs {
+			close(sub.events)
+		}
+	}()
+
+	return b, nil
+}
+
+// SubscribeBirths returns a channel that receives each tombstone when it
+// records a birth. The options configure this subscription only.
+func (b *Bus) SubscribeBirths(opts SubscribeOptions) <-chan *Tombstone {
+	return b.subscribe(opts, func(transitions []EventType, _ *Tombstone) bool {
+		return hasTransition(transitions, EventBirth)
+	})
+}
+
+// SubscribeDeaths returns a channel that receives each tombstone when it
+// records a death. The options configure this subscription only.
+func (b *Bus) SubscribeDeaths(opts SubscribeOptions) <-chan *Tombstone {
+	return b.subscribe(opts, func(transitions []EventType, _ *Tombstone) bool {
+		return hasTransition(transitions, EventDeath)
+	})
+}
+
+// SubscribeName returns a channel that receives the named tombstone each
+// time it is written. Removal is not delivered. The options configure this
+// subscription only.
+func (b *Bus) SubscribeName(name string, opts SubscribeOptions) <-chan *Tombstone {
+	return b.subscribe(opts, func(_ []EventType, ts *Tombstone) bool {
+		return ts.Name == name
+	})
+}
+
+// Dropped returns the number of events dropped due to overflow, across all
+// subscriptions.
+func (b *Bus) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+func (b *Bus) subscribe(opts SubscribeOptions, match func([]EventType, *Tombstone) bool) <-chan *Tombstone {
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = DefaultSubscribeBuffer
+	}
+	sub := &busSubscriber{
+		events:   make(chan *Tombstone, buffer),
+		overflow: opts.Overflow,
+		match:    match,
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		close(sub.events)
+		return sub.events
+	}
+	b.subs = append(b.subs, sub)
+	return sub.events
+}
+
+// publish is the TombstoneHandler that drives all subscriptions.
+func (b *Bus) publish(event EventType, name string, ts *Tombstone) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.publishLocked(event, name, ts)
+}
+
+// publishLocked publishes a change. The bus lock must be held.
+func (b *Bus) publishLocked(event EventType, name string, ts *Tombstone) {
+	if event == EventRemove {
+		delete(b.prev, name)
+		return
+	}
+	if isStale(b.prev[name], ts) {
+		return
+	}
+	changes := transitions(b.prev[name], ts)
+	b.prev[name] = ts
+
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		if sub.match(changes, ts) {
+			b.send(sub, ts)
+		}
+	}
+}
+
+// send applies the subscriber's overflow policy, like Subscription.send.
+// The bus lock must be held.
+func (b *Bus) send(sub *busSubscriber, ts *Tombstone) {
+	switch sub.overflow {
+	case DropNewest:
+		select {
+		case sub.events <- ts:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case sub.events <- ts:
+				return
+			default:
+			}
+			// full: discard the oldest, unless the reader beat us to it
+			select {
+			case <-sub.events:
+				atomic.AddUint64(&b.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case sub.events <- ts:
+		case <-b.ctx.Done():
+		}
+	}
+}
+
+func hasTransition(transitions []EventType, event EventType) bool {
+	for _, transition := range transitions {
+		if transition == event {
+			return true
+		}
+	}
+	return false
+}
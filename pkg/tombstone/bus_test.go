@@ -0,0 +1,203 @@
+package tombstone
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestBus returns a Bus that stops when the test ends.
+func newTestBus(t *testing.T, graveyard string) *Bus {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	bus, err := NewBus(ctx, graveyard)
+	if err != nil {
+		t.Fatalf("failed to create bus: %v", err)
+	}
+	return bus
+}
+
+// receive waits for a tombstone with the name, or fails the test.
+func receive(t *testing.T, events <-chan *Tombstone, name string) *Tombstone {
+	t.Helper()
+	select {
+	case ts, ok := <-events:
+		if !ok {
+			t.Fatalf("expected %s, got closed channel", name)
+		}
+		if ts.Name != name {
+			t.Fatalf("expected %s, got: %s", name, ts)
+		}
+		return ts
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", name)
+		return nil
+	}
+}
+
+// expectNoTombstone fails the test if a tombstone is received soon.
+func expectNoTombstone(t *testing.T, events <-chan *Tombstone) {
+	t.Helper()
+	select {
+	case ts := <-events:
+		t.Fatalf("expected no tombstone, got: %s", ts)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestBus(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	existing := mustRecordBirth(t, graveyard, "existing")
+
+	bus := newTestBus(t, graveyard)
+	births := bus.SubscribeBirths(SubscribeOptions{})
+	deaths := bus.SubscribeDeaths(SubscribeOptions{})
+	app := bus.SubscribeName("app", SubscribeOptions{})
+
+	ts := mustRecordBirth(t, graveyard, "app")
+	receive(t, births, "app")
+	receive(t, app, "app")
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	if got := receive(t, deaths, "app"); got.Died == nil {
+		t.Fatalf("expected death, got: %s", got)
+	}
+	// every write is delivered, so skip to the death
+	for got := receive(t, app, "app"); got.Died == nil; got = receive(t, app, "app") {
+	}
+
+	// existing tombstones are not new births
+	if err := existing.RecordDeath(1); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	receive(t, deaths, "existing")
+	expectNoTombstone(t, births)
+}
+
+func TestBusRebirth(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	bus := newTestBus(t, graveyard)
+	births := bus.SubscribeBirths(SubscribeOptions{})
+
+	ts := mustRecordBirth(t, graveyard, "app")
+	first := receive(t, births, "app")
+	time.Sleep(10 * time.Millisecond)
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if second := receive(t, births, "app"); !second.Born.After(*first.Born) {
+		t.Fatalf("expected rebirth after %s, got: %s", first.Born, second.Born)
+	}
+
+	// heartbeats are not births
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	expectNoTombstone(t, births)
+}
+
+func TestBusStale(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	bus := newTestBus(t, graveyard)
+	births := bus.SubscribeBirths(SubscribeOptions{})
+	deaths := bus.SubscribeDeaths(SubscribeOptions{})
+
+	ts := mustRecordDeath(t, graveyard, "app", 0)
+	receive(t, deaths, "app")
+	for len(births) > 0 {
+		<-births
+	}
+
+	// ex: read before the death, but published after it
+	born := *ts.Born
+	bus.publish(EventWrite, "app", &Tombstone{Name: "app", Born: &born})
+	// then re-read
+	bus.publish(EventWrite, "app", mustRead(t, graveyard, "app"))
+	expectNoTombstone(t, births)
+	expectNoTombstone(t, deaths)
+}
+
+func TestBusConcurrentSubscribers(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	bus := newTestBus(t, graveyard)
+
+	const count = 5
+	var subs []<-chan *Tombstone
+	for i := 0; i < count; i++ {
+		subs = append(subs, bus.SubscribeDeaths(SubscribeOptions{}))
+	}
+
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func(sub <-chan *Tombstone) {
+			defer wg.Done()
+			receive(t, sub, "app")
+		}(sub)
+	}
+	mustRecordDeath(t, graveyard, "app", 0)
+	wg.Wait()
+}
+
+func TestBusClosed(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	bus, err := NewBus(ctx, graveyard)
+	if err != nil {
+		t.Fatalf("failed to create bus: %v", err)
+	}
+	births := bus.SubscribeBirths(SubscribeOptions{})
+	cancel()
+
+	select {
+	case _, ok := <-births:
+		if ok {
+			t.Fatal("expected closed channel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+
+	// subscribing after close returns a closed channel
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := <-bus.SubscribeDeaths(SubscribeOptions{}); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected closed channel")
+		}
+	}
+}
+
+func TestBusOverflowPerSubscriber(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	bus := newTestBus(t, graveyard)
+	oldest := bus.SubscribeBirths(SubscribeOptions{Buffer: 1, Overflow: DropOldest})
+	newest := bus.SubscribeBirths(SubscribeOptions{Buffer: 1, Overflow: DropNewest})
+	all := bus.SubscribeBirths(SubscribeOptions{Buffer: 3})
+
+	for _, name := range []string{"a", "b", "c"} {
+		mustRecordBirth(t, graveyard, name)
+	}
+	// each buffered separately
+	receive(t, all, "a")
+	receive(t, all, "b")
+	receive(t, all, "c")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.Dropped() < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 4 dropped, got: %d", bus.Dropped())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the latest is kept
+	receive(t, oldest, "c")
+	// the first is kept
+	receive(t, newest, "a")
+}
@@ -0,0 +1,46 @@
+package tombstone
+
+import (
+	"fmt"
+)
+
+// CascadePolicy defines whether the death of a process should shut down its
+// dependents (the processes with it as a death dep).
+type CascadePolicy string
+
+const (
+	// CascadeAlways shuts down dependents on any death. This is the default.
+	CascadeAlways CascadePolicy = "always"
+	// CascadeOnFailure shuts down dependents only if the process didn't
+	// succeed (see Succeeded), so they can proceed after a clean exit.
+	CascadeOnFailure CascadePolicy = "on-failure"
+	// CascadeNever never shuts down dependents.
+	CascadeNever CascadePolicy = "never"
+)
+
+// ParseCascadePolicy parses a policy name: always, on-failure, or never.
+func ParseCascadePolicy(name string) (CascadePolicy, error) {
+	switch policy := CascadePolicy(name); policy {
+	case CascadeAlways, CascadeOnFailure, CascadeNever:
+		return policy, nil
+	default:
+		return CascadeAlways, fmt.Errorf("unknown cascade policy: %q", name)
+	}
+}
+
+// ShouldCascade returns true if the tombstone records a death that should
+// shut down dependents, according to its CascadePolicy.
+// Unknown policies are treated as CascadeAlways, to fail safe.
+func (t *Tombstone) ShouldCascade() bool {
+	if t.Died == nil {
+		return false
+	}
+	switch t.CascadePolicy {
+	case CascadeNever:
+		return false
+	case CascadeOnFailure:
+		return !t.Succeeded(t.SuccessCodes)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,111 @@
+package tombstone
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseCascadePolicy(t *testing.T) {
+	for _, name := range []string{"always", "on-failure", "never"} {
+		policy, err := ParseCascadePolicy(name)
+		if err != nil || string(policy) != name {
+			t.Fatalf("expected policy %s, got %s: %v", name, policy, err)
+		}
+	}
+	if _, err := ParseCascadePolicy("sometimes"); err == nil {
+		t.Fatalf("expected unknown policy to fail")
+	}
+}
+
+func TestShouldCascade(t *testing.T) {
+	now := time.Now()
+	code := func(c int) *int { return &c }
+
+	tests := []struct {
+		name string
+		ts   *Tombstone
+		want bool
+	}{
+		{name: "alive", ts: &Tombstone{Born: &now}, want: false},
+		{name: "default", ts: &Tombstone{Died: &now, ExitCode: code(0)}, want: true},
+		{name: "always", ts: &Tombstone{Died: &now, ExitCode: code(0), CascadePolicy: CascadeAlways}, want: true},
+		{name: "never", ts: &Tombstone{Died: &now, ExitCode: code(1), CascadePolicy: CascadeNever}, want: false},
+		{name: "on-failure succeeded", ts: &Tombstone{Died: &now, ExitCode: code(0), CascadePolicy: CascadeOnFailure}, want: false},
+		{name: "on-failure success code", ts: &Tombstone{Died: &now, ExitCode: code(2), SuccessCodes: []int{2}, CascadePolicy: CascadeOnFailure}, want: false},
+		{name: "on-failure failed", ts: &Tombstone{Died: &now, ExitCode: code(1), CascadePolicy: CascadeOnFailure}, want: true},
+		{name: "on-failure unknown exit code", ts: &Tombstone{Died: &now, CascadePolicy: CascadeOnFailure}, want: true},
+		{name: "unknown policy", ts: &Tombstone{Died: &now, ExitCode: code(0), CascadePolicy: "sometimes"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ts.ShouldCascade(); got != tt.want {
+				t.Fatalf("expected cascade %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// recordDeathWithPolicy records a birth and death with the cascade policy.
+func recordDeathWithPolicy(t *testing.T, graveyard, name string, policy CascadePolicy, exitCode int) {
+	t.Helper()
+	ts := &Tombstone{Graveyard: graveyard, Name: name, CascadePolicy: policy}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordDeath(exitCode); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+}
+
+func TestRunWithDeathDepsCascadePolicy(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	recordDeathWithPolicy(t, graveyard, "clean", CascadeOnFailure, 0)
+	recordDeathWithPolicy(t, graveyard, "ignored", CascadeNever, 1)
+	cmd, exited := startProcess(t, "sleep", "30")
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-exited:
+			t.Errorf("expected process to survive deaths that don't cascade")
+			return
+		default:
+		}
+		recordDeathWithPolicy(t, graveyard, "failed", CascadeOnFailure, 1)
+	}()
+
+	deps := []string{"clean", "ignored", "failed"}
+	err := RunWithDeathDeps(context.Background(), graveyard, deps, cmd.Process, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected process to be terminated, got: %v", err)
+	}
+	expectSignaled(t, exited, syscall.SIGTERM)
+}
+
+func TestShouldCascadeMinimal(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	for name, policy := range map[string]CascadePolicy{"clean": CascadeOnFailure, "ignored": CascadeNever} {
+		born := time.Now()
+		died := born.Add(time.Second)
+		exitCode := 0
+		ts := &Tombstone{
+			Graveyard:     graveyard,
+			Name:          name,
+			Born:          &born,
+			Died:          &died,
+			ExitCode:      &exitCode,
+			CascadePolicy: policy,
+			OnNoSpace:     NoSpaceMinimal,
+		}
+		if err := ts.handleNoSpace(errNoSpace); err != nil {
+			t.Fatalf("expected minimal write to succeed, got: %v", err)
+		}
+
+		// not read back as the default, always
+		if got := mustRead(t, graveyard, name); got.CascadePolicy != policy || got.ShouldCascade() {
+			t.Fatalf("expected %s death not to cascade, got: %s", policy, got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+	"time"
+)
+
+// pollInterval is how often RunWithDeathDeps checks if a terminated process
+// has exited.
+const pollInterval = 100 * time.Millisecond
+
+// WaitForDeath blocks until the named tombstone records a death, or the
+// context is done.
+func WaitForDeath(ctx context.Context, graveyard, name string, opts ...Option) error {
+	_, _, err := await(ctx, graveyard, []string{name}, 1, newOptions(opts), died)
+	if err != nil {
+		return fmt.Errorf("waiting for death of %s: %v", name, err)
+	}
+	return nil
+}
+
+// WaitForTerminating blocks until the named tombstone records that it is
+// terminating or has died, or the context is done.
+func WaitForTerminating(ctx context.Context, graveyard, name string, opts ...Option) error {
+	_, _, err := await(ctx, graveyard, []string{name}, 1, newOptions(opts), dying)
+	if err != nil {
+		return fmt.Errorf("waiting for termination of %s: %v", name, err)
+	}
+	return nil
+}
+
+// RunWithDeathDeps blocks until any of the named death deps records a death
+// that should cascade (see ShouldCascade), and then gracefully terminates the
+// process: TERM, and then KILL, if it hasn't exited after the grace period.
+// If a death dep is already dead, the process is terminated immediately. If
+// the context is done first, the process is left running and the context
+// error is returned.
+//
+// The caller is expected to Wait for the process, so that its exit can be
+// observed.
+func RunWithDeathDeps(ctx context.Context, graveyard string, deathDeps []string, proc *os.Process, grace time.Duration, opts ...Option) error {
+	if len(deathDeps) == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	dead, _, err := await(ctx, graveyard, deathDeps, 1, newOptions(opts), cascading)
+	if err != nil {
+		return err
+	}
+	log.Printf("Death dep died: %s\n", dead[0])
+
+	return terminate(proc, grace)
+}
+
+// terminate sends TERM to the process and KILL if it hasn't exited after the
+// grace period.
+func terminate(proc *os.Process, grace time.Duration) error {
+	log.Printf("Terminating process: %d\n", proc.Pid)
+	err := proc.Signal(syscall.SIGTERM)
+	if err != nil {
+		if isProcessDone(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to terminate process: %v", err)
+	}
+
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		time.Sleep(pollInterval)
+		// signal 0 checks for existence without signaling
+		err = proc.Signal(syscall.Signal(0))
+		if err != nil {
+			if isProcessDone(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to check process: %v", err)
+		}
+	}
+
+	log.Printf("Grace period elapsed: %s: killing process: %d\n", grace, proc.Pid)
+	err = proc.Signal(syscall.SIGKILL)
+	if err != nil && !isProcessDone(err) {
+		return fmt.Errorf("failed to kill process: %v", err)
+	}
+	return nil
+}
+
+// isProcessDone returns true if the error means the process already exited.
+// os.ErrProcessDone requires go 1.16, so match the message instead.
+func isProcessDone(err error) bool {
+	return err.Error() == "os: process already finished" || errors.Is(err, syscall.ESRCH)
+}
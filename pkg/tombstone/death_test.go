@@ -0,0 +1,227 @@
+package tombstone
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startProcess starts the command and waits for it in the background.
+// The returned channel receives the wait status when it exits.
+func startProcess(t *testing.T, name string, args ...string) (*exec.Cmd, <-chan syscall.WaitStatus) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start %s: %v", name, err)
+	}
+	exited := make(chan syscall.WaitStatus, 1)
+	go func() {
+		cmd.Wait()
+		exited <- cmd.ProcessState.Sys().(syscall.WaitStatus)
+	}()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+	})
+	return cmd, exited
+}
+
+// mustRecordDeath records a birth and death for the named tombstone or fails
+// the test.
+func mustRecordDeath(t *testing.T, graveyard, name string, exitCode int) *Tombstone {
+	t.Helper()
+	ts := &Tombstone{Graveyard: graveyard, Name: name}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth of %s: %v", name, err)
+	}
+	if err := ts.RecordDeath(exitCode); err != nil {
+		t.Fatalf("failed to record death of %s: %v", name, err)
+	}
+	return ts
+}
+
+func expectSignaled(t *testing.T, exited <-chan syscall.WaitStatus, sig syscall.Signal) {
+	t.Helper()
+	select {
+	case status := <-exited:
+		if !status.Signaled() || status.Signal() != sig {
+			t.Fatalf("expected process to exit by %v, got: %v", sig, status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for process to exit")
+	}
+}
+
+func TestRunWithDeathDeps(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	cmd, exited := startProcess(t, "sleep", "30")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ts := &Tombstone{Graveyard: graveyard, Name: "dep"}
+		if err := ts.RecordDeath(1); err != nil {
+			t.Errorf("failed to record death: %v", err)
+		}
+	}()
+
+	err := RunWithDeathDeps(context.Background(), graveyard, []string{"other", "dep"}, cmd.Process, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected process to be terminated, got: %v", err)
+	}
+	expectSignaled(t, exited, syscall.SIGTERM)
+}
+
+func TestRunWithDeathDepsAlreadyDead(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "dep", 0)
+	cmd, exited := startProcess(t, "sleep", "30")
+
+	err := RunWithDeathDeps(context.Background(), graveyard, []string{"dep"}, cmd.Process, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected process to be terminated, got: %v", err)
+	}
+	expectSignaled(t, exited, syscall.SIGTERM)
+}
+
+func TestRunWithDeathDepsKillAfterGrace(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "dep", 0)
+	// ignores TERM
+	cmd, exited := startProcess(t, "sh", "-c", `trap "" TERM; while true; do sleep 0.1; done`)
+	// give the shell time to install the trap
+	time.Sleep(100 * time.Millisecond)
+
+	err := RunWithDeathDeps(context.Background(), graveyard, []string{"dep"}, cmd.Process, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("expected process to be killed, got: %v", err)
+	}
+	expectSignaled(t, exited, syscall.SIGKILL)
+}
+
+func TestRunWithDeathDepsCanceled(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "dep")
+	cmd, exited := startProcess(t, "sleep", "30")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := RunWithDeathDeps(ctx, graveyard, []string{"dep"}, cmd.Process, time.Second)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context error, got: %v", err)
+	}
+
+	select {
+	case <-exited:
+		t.Fatalf("expected process to be left running")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWaitForDeath(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "dep")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ts := &Tombstone{Graveyard: graveyard, Name: "dep"}
+		if err := ts.RecordDeath(0); err != nil {
+			t.Errorf("failed to record death: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := WaitForDeath(ctx, graveyard, "dep"); err != nil {
+		t.Fatalf("expected death, got: %v", err)
+	}
+}
+
+func TestWaitForTerminating(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(*Tombstone) error
+	}{
+		{name: "terminating", record: (*Tombstone).RecordTerminating},
+		// in case terminating wasn't recorded
+		{name: "died", record: func(ts *Tombstone) error { return ts.RecordDeath(0) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graveyard := tempGraveyard(t)
+			ts := mustRecordBirth(t, graveyard, "dep")
+
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				if err := tt.record(ts); err != nil {
+					t.Errorf("failed to record: %v", err)
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := WaitForTerminating(ctx, graveyard, "dep"); err != nil {
+				t.Fatalf("expected termination, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRecordDeathOnce(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := mustRecordBirth(t, graveyard, "app")
+
+	if err := ts.RecordDeathOnce(2); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	died := *ts.Died
+	if err := ts.RecordDeathOnce(3); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	// the first death is preserved
+	got := mustRead(t, graveyard, "app")
+	if got.ExitCode == nil || *got.ExitCode != 2 || !got.Died.Equal(died) {
+		t.Fatalf("expected first death with exit code 2, got: %s", got)
+	}
+}
+
+func TestRecordDeathOnceOnDisk(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "app", 2)
+
+	// another tombstone, ex: after the supervisor restarted
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	if err := ts.RecordDeathOnce(3); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	if ts.ExitCode == nil || *ts.ExitCode != 2 {
+		t.Fatalf("expected death from disk to be loaded, got: %s", ts)
+	}
+	if got := mustRead(t, graveyard, "app"); *got.ExitCode != 2 {
+		t.Fatalf("expected death on disk to be preserved, got: %s", got)
+	}
+}
+
+func TestRecordDeathOnceConcurrent(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := mustRecordBirth(t, graveyard, "app")
+
+	var wg sync.WaitGroup
+	for code := 1; code <= 10; code++ {
+		wg.Add(1)
+		go func(code int) {
+			defer wg.Done()
+			if err := ts.RecordDeathOnce(code); err != nil {
+				t.Errorf("failed to record death: %v", err)
+			}
+		}(code)
+	}
+	wg.Wait()
+
+	got := mustRead(t, graveyard, "app")
+	if got.ExitCode == nil || *got.ExitCode != *ts.ExitCode {
+		t.Fatalf("expected one death to win, got %s on disk and %s in memory", got, ts)
+	}
+}
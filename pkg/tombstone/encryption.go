@@ -0,0 +1,124 @@
+package tombstone
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedHeader prefixes the first line of an encrypted tombstone file.
+// The rest of the line is the key ID. Plaintext tombstones are yaml, which
+// can't start with this header, so both can live in the same graveyard.
+const encryptedHeader = "#kubexit-encrypted:v1:"
+
+// Key is a symmetric key used to encrypt tombstone contents at rest.
+type Key struct {
+	// ID is written in the clear, so readers can pick the right key.
+	ID string
+	// Secret is an AES key: 16, 24, or 32 bytes.
+	Secret []byte
+}
+
+// NewKey validates and returns a new Key.
+func NewKey(id string, secret []byte) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("missing key id")
+	}
+	if strings.ContainsAny(id, " \t\r\n") {
+		return nil, fmt.Errorf("invalid key id: must not contain whitespace: %q", id)
+	}
+	switch len(secret) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("invalid key length: must be 16, 24, or 32 bytes: %d", len(secret))
+	}
+	return &Key{
+		ID:     id,
+		Secret: secret,
+	}, nil
+}
+
+func (k *Key) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %v", err)
+	}
+	return gcm, nil
+}
+
+// encrypt seals the plaintext with AES-GCM and prepends the header.
+// The key ID is authenticated, so it can't be swapped without detection.
+func encrypt(key *Key, plaintext []byte) ([]byte, error) {
+	gcm, err := key.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	header := encryptedHeader + key.ID + "\n"
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(header))
+
+	var buffer bytes.Buffer
+	buffer.WriteString(header)
+	buffer.WriteString(base64.StdEncoding.EncodeToString(sealed))
+	buffer.WriteRune('\n')
+	return buffer.Bytes(), nil
+}
+
+// isEncrypted returns true if the file contents start with the header.
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedHeader))
+}
+
+// encryptedKeyID returns the key ID from the header of an encrypted file.
+func encryptedKeyID(data []byte) (string, error) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return "", errors.New("malformed encryption header")
+	}
+	return string(data[len(encryptedHeader):i]), nil
+}
+
+// decrypt verifies and opens an encrypted file with the key.
+func decrypt(key *Key, data []byte) ([]byte, error) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, errors.New("malformed encryption header")
+	}
+	header := data[:i+1]
+
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data[i+1:])))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted body: %v", err)
+	}
+
+	gcm, err := key.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("malformed encrypted body: too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %v", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,139 @@
+package tombstone
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T, id string) *Key {
+	t.Helper()
+	key, err := NewKey(id, bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	return key
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	key := testKey(t, "k1")
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", Key: key}
+	if err := ts.RecordDeath(3); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(ts.Path())
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if !strings.HasPrefix(string(data), encryptedHeader+"k1\n") {
+		t.Fatalf("expected encrypted header, got: %q", data)
+	}
+	if bytes.Contains(data, []byte("ExitCode")) {
+		t.Fatalf("expected no plaintext fields, got: %q", data)
+	}
+
+	got := mustRead(t, graveyard, "app", WithKeys(key))
+	if got.ExitCode == nil || *got.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got: %v", got.ExitCode)
+	}
+	if got.Key != key {
+		t.Fatalf("expected read tombstone to keep the key, so re-writes stay encrypted")
+	}
+}
+
+func TestEncryptedPlaintextFallback(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app", WithKeys(testKey(t, "k1")))
+	if got.Born == nil {
+		t.Fatalf("expected plaintext tombstone to be readable with keys")
+	}
+	if got.Key != nil {
+		t.Fatalf("expected plaintext tombstone to have no key")
+	}
+}
+
+func TestEncryptedUnknownKey(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", Key: testKey(t, "k1")}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	_, err := Read(graveyard, "app")
+	if err == nil || !strings.Contains(err.Error(), "unknown key id") {
+		t.Fatalf("expected unknown key id error without keys, got: %v", err)
+	}
+	_, err = Read(graveyard, "app", WithKeys(testKey(t, "k2")))
+	if err == nil || !strings.Contains(err.Error(), "unknown key id") {
+		t.Fatalf("expected unknown key id error with other keys, got: %v", err)
+	}
+}
+
+func TestEncryptedWrongSecret(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", Key: testKey(t, "k1")}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	other, err := NewKey("k1", bytes.Repeat([]byte{0x24}, 32))
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	_, err = Read(graveyard, "app", WithKeys(other))
+	if err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Fatalf("expected decrypt error, got: %v", err)
+	}
+}
+
+func TestEncryptedKeyIDAuthenticated(t *testing.T) {
+	k1 := testKey(t, "k1")
+	// same secret, different id
+	k2 := testKey(t, "k2")
+
+	data, err := encrypt(k1, []byte("Born: now\n"))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	swapped := bytes.Replace(data, []byte(encryptedHeader+"k1"), []byte(encryptedHeader+"k2"), 1)
+	_, err = decrypt(k2, swapped)
+	if err == nil {
+		t.Fatalf("expected swapping the key id to fail decryption")
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		secret  []byte
+		wantErr bool
+	}{
+		{name: "aes-128", id: "a", secret: make([]byte, 16)},
+		{name: "aes-192", id: "a", secret: make([]byte, 24)},
+		{name: "aes-256", id: "a", secret: make([]byte, 32)},
+		{name: "missing id", id: "", secret: make([]byte, 32), wantErr: true},
+		{name: "whitespace id", id: "a b", secret: make([]byte, 32), wantErr: true},
+		{name: "bad length", id: "a", secret: make([]byte, 20), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKey(tt.id, tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
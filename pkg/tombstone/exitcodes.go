@@ -0,0 +1,60 @@
+package tombstone
+
+import (
+	"errors"
+	"log"
+	"os"
+)
+
+// loadExitCodeHistory carries the ExitCodeHistory forward from the prior
+// tombstone, if any, before it is replaced at birth, or at death if there
+// was no birth (ex: RecordNeverBorn). A prior tombstone without history
+// (ex: written before MaxExitCodeHistory was set) contributes its ExitCode.
+// A missing or unreadable prior tombstone starts a new history.
+func (t *Tombstone) loadExitCodeHistory() {
+	t.exitCodesLoaded = true
+
+	prior, err := Read(t.Graveyard, t.Name, WithShard(t.Shard), WithKeys(t.keys()...))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error: failed to read prior exit codes: %v\n", err)
+		}
+		return
+	}
+	if len(prior.ExitCodeHistory) > 0 {
+		t.ExitCodeHistory = prior.ExitCodeHistory
+	} else if prior.Died != nil && prior.ExitCode != nil {
+		t.ExitCodeHistory = []int{*prior.ExitCode}
+	}
+	t.trimExitCodeHistory()
+}
+
+// appendExitCode appends the code to the ExitCodeHistory, if enabled,
+// keeping only the latest MaxExitCodeHistory codes. The prior history is
+// loaded first, if not already loaded at birth.
+func (t *Tombstone) appendExitCode(code int) {
+	if t.MaxExitCodeHistory <= 0 {
+		return
+	}
+	if !t.exitCodesLoaded {
+		t.loadExitCodeHistory()
+	}
+	t.ExitCodeHistory = append(t.ExitCodeHistory, code)
+	t.trimExitCodeHistory()
+}
+
+func (t *Tombstone) trimExitCodeHistory() {
+	if extra := len(t.ExitCodeHistory) - t.MaxExitCodeHistory; extra > 0 {
+		t.ExitCodeHistory = append([]int(nil), t.ExitCodeHistory[extra:]...)
+	}
+}
+
+// ExitCodeHistogram returns the number of deaths with each exit code in the
+// ExitCodeHistory.
+func (t *Tombstone) ExitCodeHistogram() map[int]int {
+	histogram := map[int]int{}
+	for _, code := range t.ExitCodeHistory {
+		histogram[code]++
+	}
+	return histogram
+}
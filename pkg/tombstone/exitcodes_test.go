@@ -0,0 +1,73 @@
+package tombstone
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// recordLife records the birth and death of a new tombstone, as a restarted
+// container would, keeping the latest max exit codes.
+func recordLife(t *testing.T, graveyard, name string, max, code int) *Tombstone {
+	t.Helper()
+	ts := &Tombstone{Graveyard: graveyard, Name: name, MaxExitCodeHistory: max}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordDeath(code); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	return ts
+}
+
+func TestExitCodeHistory(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	for _, code := range []int{0, 1, 2, 1} {
+		recordLife(t, graveyard, "app", 3, code)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if want := []int{1, 2, 1}; !reflect.DeepEqual(got.ExitCodeHistory, want) {
+		t.Fatalf("expected the latest exit codes %v, got: %v", want, got.ExitCodeHistory)
+	}
+	if want := map[int]int{1: 2, 2: 1}; !reflect.DeepEqual(got.ExitCodeHistogram(), want) {
+		t.Fatalf("expected histogram %v, got: %v", want, got.ExitCodeHistogram())
+	}
+}
+
+func TestExitCodeHistoryDisabled(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	recordLife(t, graveyard, "app", 0, 1)
+	recordLife(t, graveyard, "app", 0, 2)
+
+	if got := mustRead(t, graveyard, "app"); got.ExitCodeHistory != nil {
+		t.Fatalf("expected no exit code history, got: %v", got.ExitCodeHistory)
+	}
+}
+
+func TestExitCodeHistoryPriorExitCode(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	// written before the history was enabled
+	mustRecordDeath(t, graveyard, "app", 3)
+	recordLife(t, graveyard, "app", 5, 0)
+
+	got := mustRead(t, graveyard, "app")
+	if want := []int{3, 0}; !reflect.DeepEqual(got.ExitCodeHistory, want) {
+		t.Fatalf("expected exit codes %v, got: %v", want, got.ExitCodeHistory)
+	}
+}
+
+func TestExitCodeHistoryNeverBorn(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	recordLife(t, graveyard, "app", 5, 1)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", MaxExitCodeHistory: 5}
+	if err := ts.RecordNeverBorn(127, errors.New("not found")); err != nil {
+		t.Fatalf("failed to record never born: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if want := []int{1, 127}; !reflect.DeepEqual(got.ExitCodeHistory, want) {
+		t.Fatalf("expected exit codes %v, got: %v", want, got.ExitCodeHistory)
+	}
+}
@@ -0,0 +1,86 @@
+package tombstone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Format is an output format for tombstone changes.
+type Format int
+
+const (
+	// FormatSummary writes each tombstone Summary as a line.
+	FormatSummary Format = iota
+	// FormatJSONL writes each change as a MirrorEvent JSON line.
+	FormatJSONL
+)
+
+// FollowAll writes the current state of every tombstone in the graveyard to
+// w, and then writes a line for each change, until the context is done.
+// A change may be written more than once, if it happens during the initial
+// dump.
+func FollowAll(ctx context.Context, graveyard string, w io.Writer, format Format, opts ...Option) error {
+	config := newOptions(opts)
+
+	var lock sync.Mutex
+	writeLine := func(event EventType, name string, ts *Tombstone) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		line, err := formatLine(format, event, name, ts)
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			return
+		}
+		_, err = w.Write(line)
+		if err != nil {
+			log.Printf("Error: failed to write tombstone change: %v\n", err)
+		}
+	}
+
+	// watch before dumping, so no changes are missed
+	err := Watch(ctx, graveyard, readingHandler(graveyard, config, writeLine), withOptions(config))
+	if err != nil {
+		return fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+
+	tombstones, err := ReadAll(graveyard, withOptions(config))
+	if err != nil {
+		return err
+	}
+	for _, ts := range tombstones {
+		writeLine(EventWrite, ts.Name, ts)
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// formatLine formats a tombstone change as a single line.
+// The tombstone is nil if removed.
+func formatLine(format Format, event EventType, name string, ts *Tombstone) ([]byte, error) {
+	switch format {
+	case FormatJSONL:
+		line, err := json.Marshal(MirrorEvent{
+			Source:    MirrorSource,
+			Event:     event,
+			Name:      name,
+			Time:      time.Now(),
+			Tombstone: ts,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tombstone change: %v", err)
+		}
+		return append(line, '\n'), nil
+	default:
+		if ts == nil {
+			return []byte(name + " Removed\n"), nil
+		}
+		return []byte(ts.Summary() + "\n"), nil
+	}
+}
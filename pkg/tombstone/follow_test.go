@@ -0,0 +1,124 @@
+package tombstone
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a buffer that can be written and read concurrently.
+type syncBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.String()
+}
+
+// awaitLine waits for a line containing the substring to be written.
+func awaitLine(t *testing.T, b *syncBuffer, substr string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		scanner := bufio.NewScanner(strings.NewReader(b.String()))
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), substr) {
+				return scanner.Text()
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for line with %q, got:\n%s", substr, b.String())
+	return ""
+}
+
+// follow runs FollowAll until the test ends.
+func follow(t *testing.T, graveyard string, format Format) *syncBuffer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	out := &syncBuffer{}
+	go func() {
+		defer close(done)
+		if err := FollowAll(ctx, graveyard, out, format); err != nil {
+			t.Errorf("failed to follow: %v", err)
+		}
+	}()
+	return out
+}
+
+func TestFollowAllSummary(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "existing")
+
+	out := follow(t, graveyard, FormatSummary)
+	// initial dump
+	awaitLine(t, out, "existing Running")
+
+	ts := mustRecordBirth(t, graveyard, "app")
+	awaitLine(t, out, "app Running")
+	if err := ts.RecordDeath(2); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	awaitLine(t, out, "app Failed exit=2")
+}
+
+func TestFollowAllJSONL(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "existing", 0)
+
+	out := follow(t, graveyard, FormatJSONL)
+	line := awaitLine(t, out, `"Name":"existing"`)
+
+	var event MirrorEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		t.Fatalf("expected a json line, got %q: %v", line, err)
+	}
+	if event.Source != MirrorSource || event.Event != EventWrite {
+		t.Fatalf("expected a kubexit write event, got: %+v", event)
+	}
+	if event.Tombstone == nil || event.Tombstone.ExitCode == nil || *event.Tombstone.ExitCode != 0 {
+		t.Fatalf("expected tombstone with exit code 0, got: %+v", event.Tombstone)
+	}
+}
+
+func TestFormatLineRemoved(t *testing.T) {
+	line, err := formatLine(FormatSummary, EventRemove, "app", nil)
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	if string(line) != "app Removed\n" {
+		t.Fatalf("expected removed line, got: %q", line)
+	}
+
+	line, err = formatLine(FormatJSONL, EventRemove, "app", nil)
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	var event MirrorEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		t.Fatalf("expected a json line, got %q: %v", line, err)
+	}
+	if event.Event != EventRemove || event.Name != "app" || event.Tombstone != nil {
+		t.Fatalf("expected remove event without a tombstone, got: %+v", event)
+	}
+}
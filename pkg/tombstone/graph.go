@@ -0,0 +1,107 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// RecordDependencies records the declared birth and death deps, so the
+// coordination graph can be discovered from the graveyard (see BuildGraph).
+func (t *Tombstone) RecordDependencies(ctx context.Context, birthDeps, deathDeps []string) error {
+	err := ctx.Err()
+	if err != nil {
+		return fmt.Errorf("failed to update tombstone dependencies: %v", err)
+	}
+
+	log.Printf("Updating tombstone: %s\n", t.Path())
+	err = t.writeEvent(EventWrite, func() bool {
+		t.BirthDeps = birthDeps
+		t.DeathDeps = deathDeps
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tombstone dependencies: %v", err)
+	}
+	return nil
+}
+
+// BuildGraph reads all the tombstones in a graveyard and returns the
+// declared birth and death deps of each, keyed by tombstone name.
+// Tombstones without deps of a kind are omitted from that graph.
+func BuildGraph(graveyard string, opts ...Option) (birthGraph, deathGraph map[string][]string, err error) {
+	tombstones, err := ReadAll(graveyard, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	birthGraph = map[string][]string{}
+	deathGraph = map[string][]string{}
+	for _, ts := range tombstones {
+		if len(ts.BirthDeps) > 0 {
+			birthGraph[ts.Name] = ts.BirthDeps
+		}
+		if len(ts.DeathDeps) > 0 {
+			deathGraph[ts.Name] = ts.DeathDeps
+		}
+	}
+	return birthGraph, deathGraph, nil
+}
+
+// EdgeLatencies returns, for each dependent and each of its deps (ex: the
+// birth graph from BuildGraph), how long the dependent took to be born after
+// the dep was ready (or born, if Ready wasn't recorded), keyed by dependent
+// and then dep. Edges where either hasn't been born (or has no tombstone)
+// are omitted.
+func EdgeLatencies(graveyard string, deps map[string][]string, opts ...Option) (map[string]map[string]time.Duration, error) {
+	config := newOptions(opts)
+
+	cache := map[string]*Tombstone{}
+	read := func(name string) (*Tombstone, error) {
+		if ts, ok := cache[name]; ok {
+			return ts, nil
+		}
+		ts, err := Read(graveyard, name, withOptions(config))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		// nil if not found
+		cache[name] = ts
+		return ts, nil
+	}
+
+	latencies := map[string]map[string]time.Duration{}
+	for dependent, depNames := range deps {
+		ts, err := read(dependent)
+		if err != nil {
+			return nil, err
+		}
+		if ts == nil || ts.Born == nil {
+			continue
+		}
+		for _, depName := range depNames {
+			dep, err := read(depName)
+			if err != nil {
+				return nil, err
+			}
+			if dep == nil {
+				continue
+			}
+			ready := dep.Ready
+			if ready == nil {
+				ready = dep.Born
+			}
+			if ready == nil {
+				continue
+			}
+			if latencies[dependent] == nil {
+				latencies[dependent] = map[string]time.Duration{}
+			}
+			latencies[dependent][depName] = ts.Born.Sub(*ready)
+		}
+	}
+	return latencies, nil
+}
@@ -0,0 +1,96 @@
+package tombstone
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildGraph(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	deps := map[string][2][]string{
+		"app":     {{"db", "cache"}, {"db"}},
+		"sidecar": {nil, {"app"}},
+		"db":      {nil, nil},
+	}
+	for name, d := range deps {
+		ts := mustRecordBirth(t, graveyard, name)
+		if err := ts.RecordDependencies(context.Background(), d[0], d[1]); err != nil {
+			t.Fatalf("failed to record dependencies: %v", err)
+		}
+	}
+
+	birthGraph, deathGraph, err := BuildGraph(graveyard)
+	if err != nil {
+		t.Fatalf("failed to build graph: %v", err)
+	}
+	wantBirth := map[string][]string{"app": {"db", "cache"}}
+	if !reflect.DeepEqual(birthGraph, wantBirth) {
+		t.Fatalf("expected birth graph %v, got: %v", wantBirth, birthGraph)
+	}
+	wantDeath := map[string][]string{"app": {"db"}, "sidecar": {"app"}}
+	if !reflect.DeepEqual(deathGraph, wantDeath) {
+		t.Fatalf("expected death graph %v, got: %v", wantDeath, deathGraph)
+	}
+}
+
+func TestRecordDependenciesKeepsBirth(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := mustRecordBirth(t, graveyard, "app")
+	if err := ts.RecordDependencies(context.Background(), []string{"db"}, nil); err != nil {
+		t.Fatalf("failed to record dependencies: %v", err)
+	}
+	if got := mustRead(t, graveyard, "app"); got.Born == nil {
+		t.Fatalf("expected birth to be kept, got: %s", got)
+	}
+}
+
+func TestRecordDependenciesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ts := &Tombstone{Graveyard: tempGraveyard(t), Name: "app"}
+	if err := ts.RecordDependencies(ctx, []string{"db"}, nil); err == nil {
+		t.Fatalf("expected canceled context to fail")
+	}
+	if ts.BirthDeps != nil {
+		t.Fatalf("expected dependencies not to be recorded, got: %v", ts.BirthDeps)
+	}
+}
+
+func TestEdgeLatencies(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	base := time.Now().Truncate(time.Second)
+	at := func(d time.Duration) *time.Time {
+		ts := base.Add(d)
+		return &ts
+	}
+	for _, ts := range []*Tombstone{
+		{Name: "db", Born: at(0), Ready: at(2 * time.Second)},
+		{Name: "cache", Born: at(time.Second)},
+		{Name: "app", Born: at(5 * time.Second)},
+		{Name: "unborn"},
+	} {
+		ts.Graveyard = graveyard
+		if err := ts.Write(); err != nil {
+			t.Fatalf("failed to write %s: %v", ts.Name, err)
+		}
+	}
+
+	latencies, err := EdgeLatencies(graveyard, map[string][]string{
+		"app":    {"db", "cache", "missing", "unborn"},
+		"unborn": {"db"},
+		"other":  {"db"},
+	})
+	if err != nil {
+		t.Fatalf("failed to compute edge latencies: %v", err)
+	}
+	// ready preferred over born; missing and unborn edges omitted
+	want := map[string]map[string]time.Duration{
+		"app": {"db": 3 * time.Second, "cache": 4 * time.Second},
+	}
+	if !reflect.DeepEqual(latencies, want) {
+		t.Fatalf("expected latencies %v, got: %v", want, latencies)
+	}
+}
@@ -0,0 +1,120 @@
+package tombstone
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Graveyard is a handle to a graveyard directory that can be moved while in
+// use. Reads and writes through the handle always use the active directory.
+type Graveyard struct {
+	// lock is held for reading by in-flight operations and for writing
+	// while switching directories
+	lock   sync.RWMutex
+	dir    string
+	config options
+}
+
+// NewGraveyard returns a handle to the graveyard directory.
+// The options are used for all reads and writes.
+func NewGraveyard(dir string, opts ...Option) *Graveyard {
+	return &Graveyard{
+		dir:    dir,
+		config: newOptions(opts),
+	}
+}
+
+// Dir returns the active graveyard directory.
+func (g *Graveyard) Dir() string {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.dir
+}
+
+// Read a tombstone from the active directory.
+func (g *Graveyard) Read(name string) (*Tombstone, error) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return Read(g.dir, name, withOptions(g.config))
+}
+
+// ReadAll reads all the tombstones in the active directory.
+func (g *Graveyard) ReadAll() ([]*Tombstone, error) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return ReadAll(g.dir, withOptions(g.config))
+}
+
+// Write the tombstone to the active directory.
+// The tombstone Graveyard and Shard are updated to match.
+func (g *Graveyard) Write(t *Tombstone) error {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	t.Graveyard = g.dir
+	t.Shard = g.config.shard
+	return t.Write()
+}
+
+// SwapDir waits for in-flight reads and writes to finish and then switches
+// the active directory. Existing tombstones are not moved.
+// Watches started before the swap keep watching the old directory.
+func (g *Graveyard) SwapDir(newDir string) error {
+	return g.swapDir(newDir, false)
+}
+
+// MigrateDir is like SwapDir, but first copies the existing tombstones into
+// the new directory. Tombstones in the old directory are left in place.
+func (g *Graveyard) MigrateDir(newDir string) error {
+	return g.swapDir(newDir, true)
+}
+
+func (g *Graveyard) swapDir(newDir string, migrate bool) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	err := os.MkdirAll(newDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create graveyard: %v", err)
+	}
+
+	if migrate {
+		err = copyTombstones(g.dir, newDir, g.config)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Graveyard moved: %s -> %s\n", g.dir, newDir)
+	g.dir = newDir
+	return nil
+}
+
+// copyTombstones copies the tombstone files, as is, to another graveyard.
+func copyTombstones(oldDir, newDir string, config options) error {
+	names, err := listNames(oldDir, config)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		src := (&Tombstone{Graveyard: oldDir, Name: name, Shard: config.shard}).Path()
+		dst := (&Tombstone{Graveyard: newDir, Name: name, Shard: config.shard}).Path()
+
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("failed to migrate tombstone: %v", err)
+		}
+		err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("failed to migrate tombstone: %v", err)
+		}
+		err = writeFileAtomic(dst, data)
+		if err != nil {
+			return fmt.Errorf("failed to migrate tombstone: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package tombstone
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestGraveyardSwapDir(t *testing.T) {
+	oldDir := tempGraveyard(t)
+	newDir := tempGraveyard(t)
+	g := NewGraveyard(oldDir)
+
+	if err := g.Write(&Tombstone{Name: "before"}); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if err := g.SwapDir(newDir); err != nil {
+		t.Fatalf("failed to swap: %v", err)
+	}
+	if g.Dir() != newDir {
+		t.Fatalf("expected active dir %s, got: %s", newDir, g.Dir())
+	}
+
+	ts := &Tombstone{Name: "after"}
+	if err := g.Write(ts); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if ts.Graveyard != newDir {
+		t.Fatalf("expected tombstone graveyard %s, got: %s", newDir, ts.Graveyard)
+	}
+	if _, err := g.Read("after"); err != nil {
+		t.Fatalf("expected tombstone in new dir: %v", err)
+	}
+	// not moved
+	if _, err := g.Read("before"); err == nil {
+		t.Fatalf("expected old tombstone to stay in the old dir")
+	}
+	mustRead(t, oldDir, "before")
+}
+
+func TestGraveyardMigrateDir(t *testing.T) {
+	oldDir := tempGraveyard(t)
+	newDir := tempGraveyard(t)
+	g := NewGraveyard(oldDir, WithShard(HashShard))
+
+	for _, name := range []string{"a", "b"} {
+		if err := g.Write(&Tombstone{Name: name}); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	if err := g.MigrateDir(newDir); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	tombstones, err := g.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read all: %v", err)
+	}
+	if len(tombstones) != 2 {
+		t.Fatalf("expected 2 migrated tombstones, got: %d", len(tombstones))
+	}
+	// left in place
+	mustRead(t, oldDir, "a", WithShard(HashShard))
+}
+
+func TestGraveyardSwapDirWhileWriting(t *testing.T) {
+	dirs := []string{tempGraveyard(t), tempGraveyard(t)}
+	g := NewGraveyard(dirs[0])
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ts := &Tombstone{Name: fmt.Sprintf("app-%d-%d", i, j)}
+				if err := g.Write(ts); err != nil {
+					t.Errorf("failed to write: %v", err)
+					return
+				}
+				// written to the dir that was active when the write started
+				if _, err := Read(ts.Graveyard, ts.Name); err != nil {
+					t.Errorf("expected tombstone in %s: %v", ts.Graveyard, err)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		if err := g.SwapDir(dirs[i%2]); err != nil {
+			t.Fatalf("failed to swap: %v", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,56 @@
+package tombstone
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// CoordinationHealth summarizes whether the coordination of a pod's
+// containers is in a steady, usable state.
+type CoordinationHealth struct {
+	// Healthy is true if no expected containers are missing, failed, or
+	// orphaned.
+	Healthy bool
+	// Missing containers have not been born.
+	Missing []string
+	// Failed containers never started (ex: exec failed).
+	Failed []string
+	// Orphaned containers are alive, but their heartbeat is stale.
+	Orphaned []string
+	// Ready containers are alive, with a fresh heartbeat.
+	Ready []string
+	// Dead containers were born and have since died.
+	Dead []string
+}
+
+// PodCoordinationHealth checks the tombstones of the expected containers.
+// See FindOrphans for how staleness is determined.
+// Each container is listed once, in the order expected.
+func PodCoordinationHealth(graveyard string, expected []string, staleAfter time.Duration, now time.Time, opts ...Option) (CoordinationHealth, error) {
+	health := CoordinationHealth{}
+	for _, name := range expected {
+		ts, err := Read(graveyard, name, opts...)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return health, err
+			}
+			health.Missing = append(health.Missing, name)
+			continue
+		}
+		switch {
+		case ts.NeverBorn:
+			health.Failed = append(health.Failed, name)
+		case ts.Born == nil:
+			health.Missing = append(health.Missing, name)
+		case ts.Died != nil:
+			health.Dead = append(health.Dead, name)
+		case ts.IsOrphan(staleAfter, now):
+			health.Orphaned = append(health.Orphaned, name)
+		default:
+			health.Ready = append(health.Ready, name)
+		}
+	}
+	health.Healthy = len(health.Missing) == 0 && len(health.Failed) == 0 && len(health.Orphaned) == 0
+	return health, nil
+}
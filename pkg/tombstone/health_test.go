@@ -0,0 +1,67 @@
+package tombstone
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPodCoordinationHealth(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "ready")
+	mustRecordDeath(t, graveyard, "dead", 0)
+	never := &Tombstone{Graveyard: graveyard, Name: "never"}
+	if err := never.RecordNeverBorn(127, nil); err != nil {
+		t.Fatalf("failed to record never born: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	orphan := &Tombstone{Graveyard: graveyard, Name: "orphan", Born: &old}
+	if err := orphan.Write(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	expected := []string{"ready", "orphan", "missing", "never", "dead"}
+	health, err := PodCoordinationHealth(graveyard, expected, time.Minute, time.Now())
+	if err != nil {
+		t.Fatalf("failed to check health: %v", err)
+	}
+
+	want := CoordinationHealth{
+		Healthy:  false,
+		Missing:  []string{"missing"},
+		Failed:   []string{"never"},
+		Orphaned: []string{"orphan"},
+		Ready:    []string{"ready"},
+		Dead:     []string{"dead"},
+	}
+	if !reflect.DeepEqual(health, want) {
+		t.Fatalf("expected health %+v, got: %+v", want, health)
+	}
+}
+
+func TestPodCoordinationHealthy(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "a")
+	mustRecordDeath(t, graveyard, "b", 1)
+
+	// dead containers don't make the pod unhealthy
+	health, err := PodCoordinationHealth(graveyard, []string{"a", "b"}, time.Minute, time.Now())
+	if err != nil {
+		t.Fatalf("failed to check health: %v", err)
+	}
+	if !health.Healthy {
+		t.Fatalf("expected healthy, got: %+v", health)
+	}
+}
+
+func TestPodCoordinationHealthReadError(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	if err := ioutil.WriteFile(filepath.Join(graveyard, "corrupt"), []byte("Born: [\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := PodCoordinationHealth(graveyard, []string{"corrupt"}, time.Minute, time.Now()); err == nil {
+		t.Fatalf("expected read error")
+	}
+}
@@ -0,0 +1,56 @@
+package tombstone
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// heartbeatSuffix is appended to the tombstone name for the heartbeat
+// sidecar file. Names with this suffix are not tombstones.
+const heartbeatSuffix = ".heartbeat"
+
+// HeartbeatPath returns the path of the heartbeat sidecar file.
+func (t *Tombstone) HeartbeatPath() string {
+	return t.Path() + heartbeatSuffix
+}
+
+// writeHeartbeatSidecar writes the LastHeartbeat to the sidecar file,
+// instead of re-writing the tombstone. The caller must hold the file lock.
+func (t *Tombstone) writeHeartbeatSidecar() error {
+	data := []byte(t.LastHeartbeat.Format(time.RFC3339Nano) + "\n")
+	err := writeFileAtomic(t.HeartbeatPath(), data)
+	if err != nil {
+		return fmt.Errorf("failed to write heartbeat file: %v", err)
+	}
+	return nil
+}
+
+// readHeartbeatSidecar merges the sidecar file timestamp into the
+// LastHeartbeat, if newer. A missing sidecar file is ignored.
+func (t *Tombstone) readHeartbeatSidecar() error {
+	data, err := ioutil.ReadFile(t.HeartbeatPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read heartbeat file: %v", err)
+	}
+	heartbeat, err := time.Parse(time.RFC3339Nano, string(bytes.TrimSpace(data)))
+	if err != nil {
+		return fmt.Errorf("failed to parse heartbeat file: %v", err)
+	}
+	if t.LastHeartbeat == nil || heartbeat.After(*t.LastHeartbeat) {
+		t.LastHeartbeat = &heartbeat
+	}
+	return nil
+}
+
+// isSidecar returns true if the file name is a sidecar, not a tombstone.
+func isSidecar(name string) bool {
+	return strings.HasSuffix(name, heartbeatSuffix)
+}
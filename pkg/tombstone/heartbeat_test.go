@@ -0,0 +1,174 @@
+package tombstone
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// sidecarTombstone records a birth with heartbeats in a sidecar file.
+func sidecarTombstone(t *testing.T, graveyard string) *Tombstone {
+	t.Helper()
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", HeartbeatSidecar: true}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	return ts
+}
+
+func TestHeartbeatSidecar(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := sidecarTombstone(t, graveyard)
+	before, err := ioutil.ReadFile(ts.Path())
+	if err != nil {
+		t.Fatalf("failed to read tombstone: %v", err)
+	}
+
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+
+	// only the sidecar is written
+	after, err := ioutil.ReadFile(ts.Path())
+	if err != nil {
+		t.Fatalf("failed to read tombstone: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatalf("expected tombstone file to be unchanged, got:\n%s", after)
+	}
+	got := mustRead(t, graveyard, "app")
+	if got.LastHeartbeat == nil || !got.LastHeartbeat.Equal(*ts.LastHeartbeat) {
+		t.Fatalf("expected heartbeat %s merged on read, got: %v", ts.LastHeartbeat, got.LastHeartbeat)
+	}
+}
+
+func TestHeartbeatSidecarOlder(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := sidecarTombstone(t, graveyard)
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	sidecar := *ts.LastHeartbeat
+
+	// a later write of the tombstone includes a newer heartbeat
+	newer := sidecar.Add(time.Minute)
+	ts.LastHeartbeat = &newer
+	if err := ts.Write(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.LastHeartbeat == nil || !got.LastHeartbeat.Equal(newer) {
+		t.Fatalf("expected newer heartbeat %s kept, got: %v", newer, got.LastHeartbeat)
+	}
+}
+
+func TestHeartbeatSidecarCorrupt(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := sidecarTombstone(t, graveyard)
+	if err := ioutil.WriteFile(ts.HeartbeatPath(), []byte("yesterday\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := Read(graveyard, "app"); err == nil {
+		t.Fatalf("expected corrupt heartbeat file to fail")
+	}
+}
+
+func TestHeartbeatSidecarNotListed(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := sidecarTombstone(t, graveyard)
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+
+	tombstones, err := ReadAll(graveyard)
+	if err != nil {
+		t.Fatalf("failed to read all: %v", err)
+	}
+	if len(tombstones) != 1 || tombstones[0].Name != "app" {
+		t.Fatalf("expected only the app tombstone, got: %v", tombstones)
+	}
+}
+
+func TestReapRemovesHeartbeatSidecar(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", HeartbeatSidecar: true}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	if _, err := ReapWhere(graveyard, func(*Tombstone) bool { return true }); err != nil {
+		t.Fatalf("failed to reap: %v", err)
+	}
+	if _, err := os.Stat(ts.HeartbeatPath()); !os.IsNotExist(err) {
+		t.Fatalf("expected heartbeat file to be removed, got: %v", err)
+	}
+}
+
+func TestHeartbeatThrottle(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", MinHeartbeatInterval: time.Hour}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	// the first heartbeat is written
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	first := *ts.LastHeartbeat
+	time.Sleep(time.Millisecond)
+
+	// later heartbeats are throttled
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	latest := *ts.LastHeartbeat
+	if got := mustRead(t, graveyard, "app"); !got.LastHeartbeat.Equal(first) {
+		t.Fatalf("expected throttled heartbeat not to be written, got: %s", got.LastHeartbeat)
+	}
+
+	if err := ts.FlushHeartbeat(); err != nil {
+		t.Fatalf("failed to flush heartbeat: %v", err)
+	}
+	if got := mustRead(t, graveyard, "app"); !got.LastHeartbeat.Equal(latest) {
+		t.Fatalf("expected flushed heartbeat %s, got: %s", latest, got.LastHeartbeat)
+	}
+}
+
+func TestFlushHeartbeatNotPending(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", MinHeartbeatInterval: time.Hour}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	// includes the throttled heartbeat
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	// nothing pending, so the tombstone isn't rewritten
+	if err := os.Remove(ts.Path()); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	if err := ts.FlushHeartbeat(); err != nil {
+		t.Fatalf("failed to flush heartbeat: %v", err)
+	}
+	if _, err := os.Stat(ts.Path()); !os.IsNotExist(err) {
+		t.Fatalf("expected no write, got: %v", err)
+	}
+}
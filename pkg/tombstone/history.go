@@ -0,0 +1,202 @@
+package tombstone
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// historyHeader is the first line of a tombstone file in the history log
+// format. Each following line is a JSON record of the tombstone, oldest
+// first. Plaintext tombstones are yaml, which can't start with this header.
+const historyHeader = "#kubexit-history:v1\n"
+
+// Defaults for HistoryLog limits that are not positive.
+const (
+	DefaultHistoryMaxBytes   = 64 * 1024
+	DefaultHistoryMaxRecords = 10
+)
+
+// HistoryLog configures the history log format, where each write appends a
+// record to the tombstone file, instead of replacing it, so one file holds
+// both the latest state and recent history. When the file exceeds MaxBytes,
+// it is compacted to the latest MaxRecords records.
+// Encryption is not supported.
+type HistoryLog struct {
+	MaxBytes   int64
+	MaxRecords int
+}
+
+func (h *HistoryLog) maxBytes() int64 {
+	if h.MaxBytes <= 0 {
+		return DefaultHistoryMaxBytes
+	}
+	return h.MaxBytes
+}
+
+func (h *HistoryLog) maxRecords() int {
+	if h.MaxRecords <= 0 {
+		return DefaultHistoryMaxRecords
+	}
+	return h.MaxRecords
+}
+
+// appendRecord appends the tombstone to the history log file and compacts
+// it, if too large. The caller must hold the file lock.
+func (t *Tombstone) appendRecord() error {
+	if t.Key != nil {
+		return errors.New("failed to write tombstone history: encryption not supported")
+	}
+	record, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tombstone json: %v", err)
+	}
+	record = append(record, '\n')
+
+	file, err := os.OpenFile(t.Path(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open tombstone file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat tombstone file: %w", err)
+	}
+	size := info.Size()
+	if size == 0 {
+		// one write, so readers never see the header alone
+		record = append([]byte(historyHeader), record...)
+	}
+	_, err = file.Write(record)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write tombstone file: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close tombstone file: %w", err)
+	}
+
+	if size+int64(len(record)) > t.History.maxBytes() {
+		return t.compactHistory()
+	}
+	return nil
+}
+
+// compactHistory rewrites the history log file with only the latest
+// records. The caller must hold the file lock.
+func (t *Tombstone) compactHistory() error {
+	data, err := ioutil.ReadFile(t.Path())
+	if err != nil {
+		return fmt.Errorf("failed to read tombstone file: %w", err)
+	}
+	return t.writeHistory(historyRecords(data))
+}
+
+// rewriteHistory rewrites the history log file with the latest records and
+// the tombstone as the newest record. Unlike appendRecord, any partial
+// record left by a failed append (ex: ENOSPC) is dropped. A missing file is
+// created. The caller must hold the file lock.
+func (t *Tombstone) rewriteHistory() error {
+	if t.Key != nil {
+		return errors.New("failed to write tombstone history: encryption not supported")
+	}
+	record, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tombstone json: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(t.Path())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read tombstone file: %w", err)
+	}
+	var records [][]byte
+	if isHistoryLog(data) {
+		records = historyRecords(data)
+	}
+	return t.writeHistory(append(records, record))
+}
+
+// writeHistory atomically writes the history log file with the latest
+// records, up to MaxRecords.
+func (t *Tombstone) writeHistory(records [][]byte) error {
+	if max := t.History.maxRecords(); len(records) > max {
+		records = records[len(records)-max:]
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(historyHeader)
+	for _, record := range records {
+		buffer.Write(record)
+		buffer.WriteRune('\n')
+	}
+	err := writeFileAtomic(t.Path(), buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to compact tombstone history: %v", err)
+	}
+	return nil
+}
+
+// isHistoryLog returns true if the file contents start with the header.
+func isHistoryLog(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(historyHeader))
+}
+
+// historyRecords returns the complete records in a history log file, oldest
+// first. A partially appended last record is skipped.
+func historyRecords(data []byte) [][]byte {
+	data = bytes.TrimPrefix(data, []byte(historyHeader))
+	var records [][]byte
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			// partial
+			break
+		}
+		record := bytes.TrimSpace(data[:i])
+		if len(record) > 0 {
+			records = append(records, record)
+		}
+		data = data[i+1:]
+	}
+	return records
+}
+
+// ReadHistory reads all the records retained in a tombstone file, oldest
+// first. Tombstones not in the history log format have a single record.
+// The heartbeat sidecar file is not merged.
+func ReadHistory(graveyard, name string, opts ...Option) ([]*Tombstone, error) {
+	config := newOptions(opts)
+
+	path := (&Tombstone{Graveyard: graveyard, Name: name, Shard: config.shard}).Path()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		// wrap, so callers can check for os.ErrNotExist
+		return nil, fmt.Errorf("failed to read tombstone file: %w", err)
+	}
+	if !isHistoryLog(data) {
+		ts, err := Read(graveyard, name, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return []*Tombstone{ts}, nil
+	}
+
+	var history []*Tombstone
+	for _, record := range historyRecords(data) {
+		ts := &Tombstone{
+			Graveyard: graveyard,
+			Name:      name,
+			Shard:     config.shard,
+		}
+		err = json.Unmarshal(record, ts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tombstone history json: %v", err)
+		}
+		history = append(history, ts)
+	}
+	return history, nil
+}
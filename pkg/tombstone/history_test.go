@@ -0,0 +1,129 @@
+package tombstone
+
+import (
+	"os"
+	"testing"
+)
+
+// mustReadHistory reads the history records or fails the test.
+func mustReadHistory(t *testing.T, graveyard, name string) []*Tombstone {
+	t.Helper()
+	records, err := ReadHistory(graveyard, name)
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	return records
+}
+
+func TestHistoryLog(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", History: &HistoryLog{}}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	if err := ts.RecordDeath(2); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	records := mustReadHistory(t, graveyard, "app")
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got: %d", len(records))
+	}
+	if records[0].Born == nil || records[0].LastHeartbeat != nil || records[0].Died != nil {
+		t.Fatalf("expected birth as the oldest record, got: %s", records[0])
+	}
+	if records[1].LastHeartbeat == nil || records[1].Died != nil {
+		t.Fatalf("expected heartbeat as the second record, got: %s", records[1])
+	}
+
+	// the latest record is read
+	got := mustRead(t, graveyard, "app")
+	if got.ExitCode == nil || *got.ExitCode != 2 {
+		t.Fatalf("expected latest death with exit code 2, got: %s", got)
+	}
+	if got.History == nil {
+		t.Fatalf("expected history log format to be kept, if re-written")
+	}
+}
+
+func TestHistoryLogCompaction(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	// small enough to compact on every write
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", History: &HistoryLog{MaxBytes: 1, MaxRecords: 2}}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := ts.Heartbeat(); err != nil {
+			t.Fatalf("failed to heartbeat: %v", err)
+		}
+	}
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	records := mustReadHistory(t, graveyard, "app")
+	if len(records) != 2 {
+		t.Fatalf("expected compaction to keep 2 records, got: %d", len(records))
+	}
+	if records[1].Died == nil || records[0].Died != nil {
+		t.Fatalf("expected the latest records, got: %s, %s", records[0], records[1])
+	}
+}
+
+func TestHistoryLogPartialRecord(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", History: &HistoryLog{}}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	// ex: a failed append, because the graveyard is full
+	file, err := os.OpenFile(ts.Path(), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if _, err := file.Write([]byte(`{"Born":"2020-`)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	file.Close()
+
+	// skipped on read
+	if records := mustReadHistory(t, graveyard, "app"); len(records) != 1 {
+		t.Fatalf("expected the partial record to be skipped, got: %d records", len(records))
+	}
+	if got := mustRead(t, graveyard, "app"); got.Born == nil {
+		t.Fatalf("expected the complete record to be read, got: %s", got)
+	}
+
+	// dropped on rewrite
+	ts.fileLock.Lock()
+	err = ts.rewriteHistory()
+	ts.fileLock.Unlock()
+	if err != nil {
+		t.Fatalf("failed to rewrite history: %v", err)
+	}
+	if records := mustReadHistory(t, graveyard, "app"); len(records) != 2 {
+		t.Fatalf("expected 2 complete records, got: %d", len(records))
+	}
+}
+
+func TestHistoryLogEncryption(t *testing.T) {
+	ts := &Tombstone{Graveyard: tempGraveyard(t), Name: "app", History: &HistoryLog{}, Key: testKey(t, "k1")}
+	if err := ts.RecordBirth(); err == nil {
+		t.Fatalf("expected encrypted history to fail")
+	}
+}
+
+func TestReadHistoryPlain(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "app", 0)
+
+	records := mustReadHistory(t, graveyard, "app")
+	if len(records) != 1 || records[0].Died == nil {
+		t.Fatalf("expected a single record, got: %v", records)
+	}
+}
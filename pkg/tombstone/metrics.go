@@ -0,0 +1,74 @@
+package tombstone
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WriteTextfileMetrics reads all the tombstones in a graveyard and writes
+// them as gauges in the Prometheus text exposition format, for the
+// node_exporter textfile collector. The file is written to a temp file and
+// renamed, so that readers never see a partial file.
+func WriteTextfileMetrics(graveyard, outPath string, opts ...Option) error {
+	tombstones, err := ReadAll(graveyard, opts...)
+	if err != nil {
+		return err
+	}
+
+	data := formatMetrics(tombstones, time.Now())
+
+	err = writeFileAtomic(outPath, data)
+	if err != nil {
+		return fmt.Errorf("failed to write metrics file: %v", err)
+	}
+	return nil
+}
+
+// formatMetrics returns the tombstones as Prometheus text format gauges.
+// The lifetime of alive containers is measured until now.
+func formatMetrics(tombstones []*Tombstone, now time.Time) []byte {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("# HELP kubexit_container_alive Whether the container is born and not dead.\n")
+	buffer.WriteString("# TYPE kubexit_container_alive gauge\n")
+	for _, ts := range tombstones {
+		alive := 0
+		if ts.Born != nil && ts.Died == nil {
+			alive = 1
+		}
+		fmt.Fprintf(&buffer, "kubexit_container_alive{name=\"%s\"} %d\n", escapeLabel(ts.Name), alive)
+	}
+
+	buffer.WriteString("# HELP kubexit_container_exit_code Exit code of the dead container.\n")
+	buffer.WriteString("# TYPE kubexit_container_exit_code gauge\n")
+	for _, ts := range tombstones {
+		if ts.ExitCode == nil {
+			continue
+		}
+		fmt.Fprintf(&buffer, "kubexit_container_exit_code{name=\"%s\"} %d\n", escapeLabel(ts.Name), *ts.ExitCode)
+	}
+
+	buffer.WriteString("# HELP kubexit_container_lifetime_seconds Time from birth until death, or until now if alive.\n")
+	buffer.WriteString("# TYPE kubexit_container_lifetime_seconds gauge\n")
+	for _, ts := range tombstones {
+		if ts.Born == nil {
+			continue
+		}
+		end := now
+		if ts.Died != nil {
+			end = *ts.Died
+		}
+		fmt.Fprintf(&buffer, "kubexit_container_lifetime_seconds{name=\"%s\"} %g\n", escapeLabel(ts.Name), end.Sub(*ts.Born).Seconds())
+	}
+
+	return buffer.Bytes()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
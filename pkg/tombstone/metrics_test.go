@@ -0,0 +1,61 @@
+package tombstone
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMetrics(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	born := now.Add(-time.Minute)
+	died := born.Add(30 * time.Second)
+	exitCode := 2
+	tombstones := []*Tombstone{
+		{Name: "alive", Born: &born},
+		{Name: "dead", Born: &born, Died: &died, ExitCode: &exitCode},
+		{Name: "pending"},
+	}
+
+	want := `# HELP kubexit_container_alive Whether the container is born and not dead.
+# TYPE kubexit_container_alive gauge
+kubexit_container_alive{name="alive"} 1
+kubexit_container_alive{name="dead"} 0
+kubexit_container_alive{name="pending"} 0
+# HELP kubexit_container_exit_code Exit code of the dead container.
+# TYPE kubexit_container_exit_code gauge
+kubexit_container_exit_code{name="dead"} 2
+# HELP kubexit_container_lifetime_seconds Time from birth until death, or until now if alive.
+# TYPE kubexit_container_lifetime_seconds gauge
+kubexit_container_lifetime_seconds{name="alive"} 60
+kubexit_container_lifetime_seconds{name="dead"} 30
+`
+	if got := string(formatMetrics(tombstones, now)); got != want {
+		t.Fatalf("expected metrics:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Fatalf("expected escaped label, got: %s", got)
+	}
+}
+
+func TestWriteTextfileMetrics(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "app", 3)
+
+	outPath := filepath.Join(tempGraveyard(t), "kubexit.prom")
+	if err := WriteTextfileMetrics(graveyard, outPath); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	data, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	if !strings.Contains(string(data), "kubexit_container_exit_code{name=\"app\"} 3\n") {
+		t.Fatalf("expected exit code gauge, got:\n%s", data)
+	}
+}
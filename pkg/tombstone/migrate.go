@@ -0,0 +1,67 @@
+package tombstone
+
+import (
+	"fmt"
+	"log"
+)
+
+// MigrateFunc upgrades a tombstone read with an old schema.
+// Returns the upgraded tombstone and true if anything changed.
+type MigrateFunc func(*Tombstone) (*Tombstone, bool)
+
+// WithMigrateOnRead makes Read apply the migration to each tombstone read.
+// If the migration changed the tombstone, it is written back atomically,
+// unless WithReadOnly is also used. This lazily migrates a graveyard as
+// tombstones are read.
+func WithMigrateOnRead(fn MigrateFunc) Option {
+	return func(c *options) {
+		c.migrate = fn
+	}
+}
+
+// WithReadOnly makes Read never write, even if migrated.
+func WithReadOnly() Option {
+	return func(c *options) {
+		c.readOnly = true
+	}
+}
+
+// migrate applies the migration and writes back the result, if changed.
+func migrate(t *Tombstone, config options) (*Tombstone, error) {
+	upgraded, changed := config.migrate(t)
+	if !changed {
+		return t, nil
+	}
+
+	// same file, even if the migration returned a new tombstone
+	upgraded.Graveyard = t.Graveyard
+	upgraded.Name = t.Name
+	upgraded.Key = t.Key
+	upgraded.Shard = t.Shard
+	upgraded.History = t.History
+
+	if config.readOnly {
+		return upgraded, nil
+	}
+
+	log.Printf("Migrating tombstone: %s\n", upgraded.Path())
+	err := upgraded.writeMigrated()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write migrated tombstone: %v", err)
+	}
+	return upgraded, nil
+}
+
+// writeMigrated writes back a migrated tombstone. History logs get the
+// migrated tombstone appended as the latest record, keeping the retained
+// history. Other tombstones are replaced atomically.
+func (t *Tombstone) writeMigrated() error {
+	if t.History != nil {
+		return t.appendRecord()
+	}
+	pretty, err := t.marshal()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.Path(), pretty)
+}
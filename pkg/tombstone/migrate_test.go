@@ -0,0 +1,87 @@
+package tombstone
+
+import "testing"
+
+// migrateStatus sets a Status on tombstones without one, returning a new
+// tombstone, like a schema upgrade would.
+func migrateStatus(t *Tombstone) (*Tombstone, bool) {
+	if t.Status != "" {
+		return t, false
+	}
+	return &Tombstone{
+		Born:     t.Born,
+		Died:     t.Died,
+		ExitCode: t.ExitCode,
+		Status:   "migrated",
+	}, true
+}
+
+func TestMigrateOnRead(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "app")
+
+	got := mustRead(t, graveyard, "app", WithMigrateOnRead(migrateStatus))
+	if got.Status != "migrated" || got.Born == nil {
+		t.Fatalf("expected migrated tombstone, got: %s", got)
+	}
+	if got.Graveyard != graveyard || got.Name != "app" {
+		t.Fatalf("expected the same file, got: %s", got.Path())
+	}
+	// written back
+	if onDisk := mustRead(t, graveyard, "app"); onDisk.Status != "migrated" {
+		t.Fatalf("expected migration to be written, got: %s", onDisk)
+	}
+}
+
+func TestMigrateOnReadUnchanged(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", Status: "current"}
+	if err := ts.Write(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app", WithMigrateOnRead(migrateStatus))
+	if got.Status != "current" {
+		t.Fatalf("expected unchanged tombstone, got: %s", got)
+	}
+}
+
+func TestMigrateOnReadReadOnly(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "app")
+
+	got := mustRead(t, graveyard, "app", WithMigrateOnRead(migrateStatus), WithReadOnly())
+	if got.Status != "migrated" {
+		t.Fatalf("expected migrated tombstone, got: %s", got)
+	}
+	if onDisk := mustRead(t, graveyard, "app"); onDisk.Status != "" {
+		t.Fatalf("expected read only migration not to be written, got: %s", onDisk)
+	}
+}
+
+func TestMigrateOnReadKeepsHistory(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", History: &HistoryLog{}}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	mustRead(t, graveyard, "app", WithMigrateOnRead(migrateStatus))
+
+	records, err := ReadHistory(graveyard, "app")
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected migration appended to 2 records, got: %d", len(records))
+	}
+	if records[0].Born == nil || records[0].Status != "" {
+		t.Fatalf("expected oldest record to be kept as is, got: %s", records[0])
+	}
+	if latest := records[2]; latest.Status != "migrated" || latest.Died == nil {
+		t.Fatalf("expected migrated death as the latest record, got: %s", latest)
+	}
+}
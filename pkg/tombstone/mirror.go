@@ -0,0 +1,61 @@
+package tombstone
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// EventType is the kind of lifecycle change that caused a tombstone write.
+type EventType string
+
+const (
+	EventWrite       EventType = "write"
+	EventBirth       EventType = "birth"
+	EventReady       EventType = "ready"
+	EventHeartbeat   EventType = "heartbeat"
+	EventTerminating EventType = "terminating"
+	EventDeath       EventType = "death"
+	EventRemove      EventType = "remove"
+)
+
+// MirrorSource tags mirrored lines, so they can be told apart from the
+// supervised process output.
+const MirrorSource = "kubexit"
+
+// MirrorEvent is the JSON line written to the Mirror for each write.
+type MirrorEvent struct {
+	Source    string
+	Event     EventType
+	Name      string
+	Time      time.Time
+	Tombstone *Tombstone
+}
+
+// mirror writes the event to the Mirror, if set, as a single JSON line.
+// Lines are written with a single Write call, so they don't interleave with
+// other writers of the same pipe (ex: the supervised process stdout).
+// The caller must hold the file lock.
+func (t *Tombstone) mirror(event EventType) {
+	if t.Mirror == nil {
+		return
+	}
+
+	line, err := json.Marshal(MirrorEvent{
+		Source:    MirrorSource,
+		Event:     event,
+		Name:      t.Name,
+		Time:      time.Now(),
+		Tombstone: t,
+	})
+	if err != nil {
+		log.Printf("Error: failed to marshal mirror event: %v\n", err)
+		return
+	}
+	line = append(line, '\n')
+
+	_, err = t.Mirror.Write(line)
+	if err != nil {
+		log.Printf("Error: failed to write mirror event: %v\n", err)
+	}
+}
@@ -0,0 +1,57 @@
+package tombstone
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMirror(t *testing.T) {
+	var buf bytes.Buffer
+	ts := &Tombstone{Graveyard: tempGraveyard(t), Name: "app", Mirror: &buf}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordTerminating(); err != nil {
+		t.Fatalf("failed to record terminating: %v", err)
+	}
+	if err := ts.RecordDeath(2); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	var events []MirrorEvent
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event MirrorEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("expected a json line, got %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	want := []EventType{EventBirth, EventTerminating, EventDeath}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got: %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Event != want[i] {
+			t.Fatalf("expected event %d to be %s, got: %s", i, want[i], event.Event)
+		}
+		if event.Source != MirrorSource || event.Name != "app" || event.Time.IsZero() {
+			t.Fatalf("expected source, name, and time, got: %+v", event)
+		}
+	}
+	last := events[len(events)-1].Tombstone
+	if last == nil || last.ExitCode == nil || *last.ExitCode != 2 {
+		t.Fatalf("expected mirrored tombstone with exit code 2, got: %v", last)
+	}
+}
+
+func TestMirrorUnset(t *testing.T) {
+	ts := &Tombstone{Graveyard: tempGraveyard(t), Name: "app"}
+	// no panic without a mirror
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package tombstone
+
+import (
+	"os"
+	"runtime"
+)
+
+// NodeInfo describes the node that ran the process, so failures can be
+// correlated with infrastructure (ex: all the crashes are on one kernel).
+type NodeInfo struct {
+	// Name is the node name (ex: from the downward API spec.nodeName).
+	Name          string `json:",omitempty"`
+	KernelVersion string `json:",omitempty"`
+	OS            string `json:",omitempty"`
+	Arch          string `json:",omitempty"`
+}
+
+// LoadNodeInfo populates the NodeInfo OS, Arch, and KernelVersion, if
+// available, keeping any Name already set. The tombstone is not written.
+func (t *Tombstone) LoadNodeInfo() {
+	if t.NodeInfo == nil {
+		t.NodeInfo = &NodeInfo{}
+	}
+	t.NodeInfo.OS = runtime.GOOS
+	t.NodeInfo.Arch = runtime.GOARCH
+	t.NodeInfo.KernelVersion = kernelVersion()
+}
+
+// LoadUserInfo populates the UID and GID with the effective user and group
+// IDs of the current process, which the supervised process inherits.
+// The tombstone is not written.
+func (t *Tombstone) LoadUserInfo() {
+	uid := os.Geteuid()
+	gid := os.Getegid()
+	t.UID = &uid
+	t.GID = &gid
+}
@@ -0,0 +1,15 @@
+package tombstone
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// kernelVersion returns the kernel release (uname -r), or empty if unknown.
+func kernelVersion() string {
+	release, err := ioutil.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(release))
+}
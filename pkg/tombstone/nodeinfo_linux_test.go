@@ -0,0 +1,26 @@
+package tombstone
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestKernelVersion(t *testing.T) {
+	var uname syscall.Utsname
+	if err := syscall.Uname(&uname); err != nil {
+		t.Fatalf("failed to uname: %v", err)
+	}
+	var release strings.Builder
+	for _, c := range uname.Release {
+		if c == 0 {
+			break
+		}
+		release.WriteByte(byte(c))
+	}
+
+	// same as uname -r
+	if got := kernelVersion(); got != release.String() {
+		t.Fatalf("expected kernel version %q, got: %q", release.String(), got)
+	}
+}
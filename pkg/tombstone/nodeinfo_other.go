@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package tombstone
+
+// kernelVersion is only supported on Linux.
+// Elsewhere, it is empty.
+func kernelVersion() string {
+	return ""
+}
@@ -0,0 +1,67 @@
+package tombstone
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadNodeInfo(t *testing.T) {
+	ts := &Tombstone{NodeInfo: &NodeInfo{Name: "node-1"}}
+	ts.LoadNodeInfo()
+
+	if ts.NodeInfo.Name != "node-1" {
+		t.Fatalf("expected name to be kept, got: %q", ts.NodeInfo.Name)
+	}
+	if ts.NodeInfo.OS != runtime.GOOS || ts.NodeInfo.Arch != runtime.GOARCH {
+		t.Fatalf("expected %s/%s, got: %s/%s", runtime.GOOS, runtime.GOARCH, ts.NodeInfo.OS, ts.NodeInfo.Arch)
+	}
+}
+
+func TestNodeInfoRoundTrip(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	ts.LoadNodeInfo()
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.NodeInfo == nil || *got.NodeInfo != *ts.NodeInfo {
+		t.Fatalf("expected node info %+v, got: %+v", ts.NodeInfo, got.NodeInfo)
+	}
+}
+
+func TestLoadUserInfo(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	ts.LoadUserInfo()
+	if ts.UID == nil || *ts.UID != os.Geteuid() || ts.GID == nil || *ts.GID != os.Getegid() {
+		t.Fatalf("expected uid %d and gid %d, got: %v, %v", os.Geteuid(), os.Getegid(), ts.UID, ts.GID)
+	}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.UID == nil || *got.UID != *ts.UID || got.GID == nil || *got.GID != *ts.GID {
+		t.Fatalf("expected uid and gid to round trip, got: %s", got)
+	}
+}
+
+func TestSummaryUserInfo(t *testing.T) {
+	uid, gid := 1000, 0
+	ts := &Tombstone{Name: "app", UID: &uid, GID: &gid}
+	if summary := ts.Summary(); !strings.Contains(summary, " uid=1000 gid=0") {
+		t.Fatalf("expected uid and gid in summary, got: %q", summary)
+	}
+
+	// omitted if not recorded
+	ts = &Tombstone{Name: "app"}
+	if summary := ts.Summary(); strings.Contains(summary, "uid=") || strings.Contains(summary, "gid=") {
+		t.Fatalf("expected no uid or gid in summary, got: %q", summary)
+	}
+}
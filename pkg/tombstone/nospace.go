@@ -0,0 +1,135 @@
+package tombstone
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"syscall"
+)
+
+// NoSpacePolicy defines what Write does when the graveyard is full (ENOSPC).
+type NoSpacePolicy int
+
+const (
+	// NoSpaceFail returns the error.
+	NoSpaceFail NoSpacePolicy = iota
+	// NoSpaceReap removes other dead tombstones, oldest death first, until
+	// the write succeeds or there are no more dead tombstones.
+	NoSpaceReap
+	// NoSpaceMinimal retries, writing only Born, Died, ExitCode, and the
+	// fields readers need to match and interpret the death: NeverBorn,
+	// SuccessCodes, CascadePolicy, PodGeneration, and PodInstanceID.
+	NoSpaceMinimal
+)
+
+// ParseNoSpacePolicy parses a policy name: fail, reap, or minimal.
+func ParseNoSpacePolicy(name string) (NoSpacePolicy, error) {
+	switch name {
+	case "fail":
+		return NoSpaceFail, nil
+	case "reap":
+		return NoSpaceReap, nil
+	case "minimal":
+		return NoSpaceMinimal, nil
+	default:
+		return NoSpaceFail, fmt.Errorf("unknown no space policy: %q", name)
+	}
+}
+
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
+
+// handleNoSpace applies the OnNoSpace policy after a failed write.
+// The caller must hold the file lock.
+func (t *Tombstone) handleNoSpace(cause error) error {
+	switch t.OnNoSpace {
+	case NoSpaceReap:
+		return t.reapAndRetry(cause)
+	case NoSpaceMinimal:
+		return t.writeMinimal(cause)
+	default:
+		return cause
+	}
+}
+
+func (t *Tombstone) reapAndRetry(cause error) error {
+	config := newOptions([]Option{WithShard(t.Shard), WithKeys(t.keys()...)})
+	names, err := listNames(t.Graveyard, config)
+	if err != nil {
+		return fmt.Errorf("%v: failed to reap: %v", cause, err)
+	}
+
+	var others []string
+	for _, name := range names {
+		if name != t.Name {
+			others = append(others, name)
+		}
+	}
+	dead := readDead(t.Graveyard, others, config)
+	sortByDeath(dead)
+
+	log.Printf("Graveyard full: reaping dead tombstones: %s\n", t.Graveyard)
+	for _, ts := range dead {
+		err = removeTombstone(ts)
+		if err != nil {
+			return fmt.Errorf("%v: failed to reap: %v", cause, err)
+		}
+		err = t.retryWrite()
+		if err == nil || !isNoSpace(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("%v: no dead tombstones left to reap", cause)
+}
+
+func (t *Tombstone) writeMinimal(cause error) error {
+	minimal := &Tombstone{
+		Born:         t.Born,
+		Died:         t.Died,
+		ExitCode:     t.ExitCode,
+		SuccessCodes: t.SuccessCodes,
+		NeverBorn:    t.NeverBorn,
+		// or dependents would be shut down by the default policy
+		CascadePolicy: t.CascadePolicy,
+		// matched by generation filters (ex: WithPodGeneration)
+		PodGeneration: t.PodGeneration,
+		PodInstanceID: t.PodInstanceID,
+
+		Graveyard:  t.Graveyard,
+		Name:       t.Name,
+		Key:        t.Key,
+		Shard:      t.Shard,
+		History:    t.History,
+		TimeFormat: t.TimeFormat,
+	}
+
+	log.Printf("Graveyard full: writing minimal tombstone: %s\n", t.Path())
+	err := minimal.retryWrite()
+	if err != nil {
+		return fmt.Errorf("%v: failed to write minimal tombstone: %v", cause, err)
+	}
+	return nil
+}
+
+// retryWrite writes the tombstone file again, after a failed write.
+// History logs are rewritten, so the retained history isn't lost, along
+// with any partial record left by the failed append.
+func (t *Tombstone) retryWrite() error {
+	if t.History != nil {
+		return t.rewriteHistory()
+	}
+	pretty, err := t.marshal()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.Path(), pretty)
+}
+
+// keys returns the tombstone Key as a list, for reading siblings.
+func (t *Tombstone) keys() []*Key {
+	if t.Key == nil {
+		return nil
+	}
+	return []*Key{t.Key}
+}
@@ -0,0 +1,81 @@
+package tombstone
+
+import (
+	"time"
+)
+
+// Option configures how tombstones are read, watched, and waited on.
+// Options that don't apply to a function are ignored.
+type Option func(*options)
+
+type options struct {
+	keys        map[string]*Key
+	generation  string
+	shard       ShardFunc
+	tick        time.Duration
+	onTick      func(time.Time)
+	debounce    time.Duration
+	flushOnStop bool
+	names       map[string]struct{}
+	concurrency int
+	symlinks    bool
+	migrate     MigrateFunc
+	readOnly    bool
+	reapAlive   bool
+	handler     EventHandler
+	pauseBuffer int
+	replay      bool
+	onReady     func()
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// withOptions copies already parsed options.
+func withOptions(o options) Option {
+	return func(c *options) {
+		*c = o
+	}
+}
+
+// WithKeys allows reading tombstones encrypted with any of the keys.
+// Plaintext tombstones are still readable.
+func WithKeys(keys ...*Key) Option {
+	return func(c *options) {
+		if c.keys == nil {
+			c.keys = map[string]*Key{}
+		}
+		for _, key := range keys {
+			c.keys[key.ID] = key
+		}
+	}
+}
+
+// WithPodGeneration makes waiting ignore tombstones from other pod
+// generations. Tombstones without a generation are also ignored.
+// An empty generation disables filtering.
+func WithPodGeneration(generation string) Option {
+	return func(c *options) {
+		c.generation = generation
+	}
+}
+
+// matches returns true if the tombstone passes the generation filter.
+func (o options) matches(t *Tombstone) bool {
+	return o.generation == "" || t.PodGeneration == o.generation
+}
+
+// WithFollowSymlinks makes ReadAll, ForEach, and Watch read symlinked
+// tombstones, following the link. By default, symlinks are skipped, to avoid
+// surprises and loops. Links that can't be resolved (ex: dangling or looping)
+// and links to directories are always skipped. Read always follows links.
+func WithFollowSymlinks(follow bool) Option {
+	return func(c *options) {
+		c.symlinks = follow
+	}
+}
@@ -0,0 +1,68 @@
+package tombstone
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// OrderViolation is a death that happened before the death of a container
+// that should have died first.
+type OrderViolation struct {
+	// Name died out of order.
+	Name string
+	Died time.Time
+	// Preceding should have died before Name.
+	Preceding string
+	// PrecedingDied is when Preceding died, or nil if still alive.
+	PrecedingDied *time.Time
+}
+
+func (v OrderViolation) String() string {
+	if v.PrecedingDied == nil {
+		return fmt.Sprintf("%s died before %s, which is still alive", v.Name, v.Preceding)
+	}
+	return fmt.Sprintf("%s died %s before %s", v.Name, v.PrecedingDied.Sub(v.Died), v.Preceding)
+}
+
+// VerifyShutdownOrder checks that the named containers died in order: each
+// after all the containers before it in the order. Containers that are still
+// alive (or have no tombstone) have not violated the order yet, but
+// containers that died before them have. The violations are returned, in
+// order.
+func VerifyShutdownOrder(graveyard string, order []string, opts ...Option) ([]OrderViolation, error) {
+	config := newOptions(opts)
+
+	died := make([]*time.Time, len(order))
+	for i, name := range order {
+		ts, err := Read(graveyard, name, withOptions(config))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		died[i] = ts.Died
+	}
+
+	var violations []OrderViolation
+	for i, name := range order {
+		if died[i] == nil {
+			// pending
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if died[j] != nil && !died[j].After(*died[i]) {
+				continue
+			}
+			violations = append(violations, OrderViolation{
+				Name:          name,
+				Died:          *died[i],
+				Preceding:     order[j],
+				PrecedingDied: died[j],
+			})
+		}
+	}
+	return violations, nil
+}
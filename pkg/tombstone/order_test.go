@@ -0,0 +1,70 @@
+package tombstone
+
+import (
+	"testing"
+	"time"
+)
+
+// writeDeaths writes tombstones that died at the offsets from start.
+// Names without an offset are alive.
+func writeDeaths(t *testing.T, graveyard string, start time.Time, died map[string]time.Duration) {
+	t.Helper()
+	for name, offset := range died {
+		ts := &Tombstone{Graveyard: graveyard, Name: name, Born: &start}
+		if offset >= 0 {
+			at := start.Add(offset)
+			ts.Died = &at
+		}
+		if err := ts.Write(); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+}
+
+func TestVerifyShutdownOrder(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeDeaths(t, graveyard, start, map[string]time.Duration{
+		"app":     1 * time.Second,
+		"sidecar": 2 * time.Second,
+		"proxy":   3 * time.Second,
+	})
+
+	violations, err := VerifyShutdownOrder(graveyard, []string{"app", "sidecar", "proxy"})
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("expected no violations, got: %v", violations)
+	}
+}
+
+func TestVerifyShutdownOrderViolations(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeDeaths(t, graveyard, start, map[string]time.Duration{
+		"app":     3 * time.Second,
+		"sidecar": 1 * time.Second,
+		"proxy":   -1,
+	})
+
+	// missing and alive containers haven't violated the order yet
+	violations, err := VerifyShutdownOrder(graveyard, []string{"proxy", "app", "sidecar", "missing"})
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+
+	want := []string{
+		"app died before proxy, which is still alive",
+		"sidecar died before proxy, which is still alive",
+		"sidecar died 2s before app",
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("expected %d violations, got: %v", len(want), violations)
+	}
+	for i, v := range violations {
+		if v.String() != want[i] {
+			t.Fatalf("expected violation %d to be %q, got: %q", i, want[i], v)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package tombstone
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// PresumedDeadStatus is the Status recorded by PresumeDead.
+const PresumedDeadStatus = "presumed dead (no heartbeat)"
+
+// MinPresumeDeadGrace is the shortest grace accepted by PresumeDead, so
+// that slow but alive processes (ex: a stalled volume) aren't presumed dead.
+const MinPresumeDeadGrace = time.Minute
+
+// FindOrphans returns the alive tombstones whose LastHeartbeat (or Born, if
+// there has been no heartbeat) is older than staleAfter, relative to now.
+// These were likely written by supervisors that crashed before recording a
+// death, and may need to be reaped or alerted on.
+func FindOrphans(graveyard string, staleAfter time.Duration, now time.Time, opts ...Option) ([]*Tombstone, error) {
+	tombstones, err := ReadAll(graveyard, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []*Tombstone
+	for _, ts := range tombstones {
+		if ts.IsOrphan(staleAfter, now) {
+			orphans = append(orphans, ts)
+		}
+	}
+	return orphans, nil
+}
+
+// IsOrphan returns true if the tombstone is alive, but its LastHeartbeat (or
+// Born, if there has been no heartbeat) is older than staleAfter.
+func (t *Tombstone) IsOrphan(staleAfter time.Duration, now time.Time) bool {
+	if t.Born == nil || t.Died != nil {
+		return false
+	}
+	return now.Sub(t.lastAlive()) > staleAfter
+}
+
+// PresumeDead records a death on the named tombstone, with the
+// PresumedDeadStatus, if it is an orphan: alive, but without a heartbeat
+// (or birth) within the grace (see IsOrphan). This lets dependents proceed
+// when a supervisor crashed or hung without recording a death. The
+// tombstone is re-read and re-checked just before writing, and written
+// atomically, so that a death or heartbeat recorded in the meantime is not
+// overwritten. The window between the re-check and the write is narrow, but
+// can't be closed, because the supervisor writes from another process.
+// Returns true if the death was recorded.
+//
+// The grace should be many heartbeat intervals, and must be at least
+// MinPresumeDeadGrace.
+func PresumeDead(graveyard, name string, grace time.Duration, now time.Time, opts ...Option) (bool, error) {
+	if grace < MinPresumeDeadGrace {
+		return false, fmt.Errorf("invalid grace: must be at least %s: %s", MinPresumeDeadGrace, grace)
+	}
+
+	ts, err := Read(graveyard, name, opts...)
+	if err != nil {
+		return false, err
+	}
+	if !ts.IsOrphan(grace, now) {
+		return false, nil
+	}
+	lastAlive := ts.lastAlive()
+
+	ts.fileLock.Lock()
+	defer ts.fileLock.Unlock()
+
+	died := now
+	ts.Died = &died
+	ts.Status = PresumedDeadStatus
+
+	var data []byte
+	if ts.History == nil {
+		data, err = ts.marshal()
+		if err != nil {
+			return false, fmt.Errorf("failed to update tombstone: %v", err)
+		}
+	}
+
+	// re-check, in case the supervisor recorded a death or heartbeat
+	latest, err := Read(graveyard, name, opts...)
+	if err != nil {
+		return false, err
+	}
+	if !latest.IsOrphan(grace, now) || !latest.lastAlive().Equal(lastAlive) {
+		return false, nil
+	}
+
+	log.Printf("Presuming dead: %s\n", ts.Path())
+	if ts.History != nil {
+		err = ts.write()
+	} else {
+		err = writeFileAtomic(ts.Path(), data)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update tombstone: %v", err)
+	}
+	return true, nil
+}
+
+// lastAlive returns the LastHeartbeat, or Born if there has been no
+// heartbeat, or zero if not born.
+func (t *Tombstone) lastAlive() time.Time {
+	switch {
+	case t.LastHeartbeat != nil:
+		return *t.LastHeartbeat
+	case t.Born != nil:
+		return *t.Born
+	default:
+		return time.Time{}
+	}
+}
+
+// PresumeOrphansDead calls PresumeDead for each orphan in the graveyard and
+// returns the names of the tombstones presumed dead.
+func PresumeOrphansDead(graveyard string, grace time.Duration, now time.Time, opts ...Option) ([]string, error) {
+	orphans, err := FindOrphans(graveyard, grace, now, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var presumed []string
+	var errs []string
+	for _, orphan := range orphans {
+		// re-checked, in case it changed since listing
+		ok, err := PresumeDead(graveyard, orphan.Name, grace, now, opts...)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if ok {
+			presumed = append(presumed, orphan.Name)
+		}
+	}
+	if len(errs) > 0 {
+		return presumed, errors.New(strings.Join(errs, "; "))
+	}
+	return presumed, nil
+}
@@ -0,0 +1,176 @@
+package tombstone
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsOrphan(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	recent := now.Add(-time.Second)
+
+	tests := []struct {
+		name string
+		ts   *Tombstone
+		want bool
+	}{
+		{name: "not born", ts: &Tombstone{}, want: false},
+		{name: "dead", ts: &Tombstone{Born: &old, Died: &old}, want: false},
+		{name: "recently born", ts: &Tombstone{Born: &recent}, want: false},
+		{name: "stale birth", ts: &Tombstone{Born: &old}, want: true},
+		{name: "recent heartbeat", ts: &Tombstone{Born: &old, LastHeartbeat: &recent}, want: false},
+		{name: "stale heartbeat", ts: &Tombstone{Born: &old, LastHeartbeat: &old}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ts.IsOrphan(time.Minute, now); got != tt.want {
+				t.Fatalf("expected orphan %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := mustRecordBirth(t, graveyard, "app")
+
+	if err := ts.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	got := mustRead(t, graveyard, "app")
+	if got.LastHeartbeat == nil || got.LastHeartbeat.Before(*got.Born) {
+		t.Fatalf("expected heartbeat after birth, got: %s", got)
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "stale")
+	mustRecordDeath(t, graveyard, "dead", 0)
+	mustRecordBirth(t, graveyard, "beating")
+
+	// later, only one is still beating
+	now := time.Now().Add(time.Hour)
+	beating := &Tombstone{Graveyard: graveyard, Name: "beating", Born: &now, LastHeartbeat: &now}
+	if err := beating.Write(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	orphans, err := FindOrphans(graveyard, time.Minute, now)
+	if err != nil {
+		t.Fatalf("failed to find orphans: %v", err)
+	}
+	if len(orphans) != 1 || orphans[0].Name != "stale" {
+		t.Fatalf("expected only stale to be an orphan, got: %v", orphans)
+	}
+}
+
+func TestPresumeDead(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "app")
+
+	// later, without a heartbeat
+	now := time.Now().Add(time.Hour)
+	ok, err := PresumeDead(graveyard, "app", time.Minute, now)
+	if err != nil {
+		t.Fatalf("failed to presume dead: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected orphan to be presumed dead")
+	}
+	got := mustRead(t, graveyard, "app")
+	if got.Died == nil || !got.Died.Equal(now) || got.Status != PresumedDeadStatus {
+		t.Fatalf("expected presumed death at %s, got: %s", now, got)
+	}
+
+	// already dead
+	ok, err = PresumeDead(graveyard, "app", time.Minute, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to presume dead: %v", err)
+	}
+	if ok {
+		t.Fatal("expected dead tombstone not to be presumed dead again")
+	}
+}
+
+func TestPresumeDeadNotOrphan(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "app")
+
+	ok, err := PresumeDead(graveyard, "app", time.Minute, time.Now())
+	if err != nil {
+		t.Fatalf("failed to presume dead: %v", err)
+	}
+	if ok {
+		t.Fatal("expected recently born tombstone not to be presumed dead")
+	}
+	if got := mustRead(t, graveyard, "app"); got.Died != nil || got.Status != "" {
+		t.Fatalf("expected tombstone to be unchanged, got: %s", got)
+	}
+}
+
+func TestPresumeDeadInvalidGrace(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "app")
+
+	_, err := PresumeDead(graveyard, "app", MinPresumeDeadGrace-time.Second, time.Now().Add(time.Hour))
+	if err == nil {
+		t.Fatal("expected error for grace below the minimum")
+	}
+	if got := mustRead(t, graveyard, "app"); got.Died != nil {
+		t.Fatalf("expected tombstone to be unchanged, got: %s", got)
+	}
+}
+
+func TestPresumeDeadHistory(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", History: &HistoryLog{}}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	ok, err := PresumeDead(graveyard, "app", time.Minute, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to presume dead: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected orphan to be presumed dead")
+	}
+
+	// appended to the history, not replacing it
+	records := mustReadHistory(t, graveyard, "app")
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got: %d", len(records))
+	}
+	if records[0].Died != nil || records[1].Status != PresumedDeadStatus {
+		t.Fatalf("expected birth then presumed death, got: %s, %s", records[0], records[1])
+	}
+}
+
+func TestPresumeOrphansDead(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "stale")
+	mustRecordDeath(t, graveyard, "dead", 0)
+
+	now := time.Now().Add(time.Hour)
+	beating := &Tombstone{Graveyard: graveyard, Name: "beating", Born: &now, LastHeartbeat: &now}
+	if err := beating.Write(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	presumed, err := PresumeOrphansDead(graveyard, time.Minute, now)
+	if err != nil {
+		t.Fatalf("failed to presume orphans dead: %v", err)
+	}
+	if !reflect.DeepEqual(presumed, []string{"stale"}) {
+		t.Fatalf("expected only stale to be presumed dead, got: %v", presumed)
+	}
+	if got := mustRead(t, graveyard, "dead"); got.Status == PresumedDeadStatus {
+		t.Fatalf("expected recorded death to be kept, got: %s", got)
+	}
+	if got := mustRead(t, graveyard, "beating"); got.Died != nil {
+		t.Fatalf("expected beating tombstone to be alive, got: %s", got)
+	}
+}
@@ -0,0 +1,172 @@
+package tombstone
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// preflightPrefix prefixes the hidden files written by Preflight.
+const preflightPrefix = ".preflight."
+
+// ErrPreflight is returned by Preflight when a step fails.
+type ErrPreflight struct {
+	// Step is the check that failed: graveyard, write, watch, or round trip.
+	Step string
+	Err  error
+}
+
+func (e *ErrPreflight) Error() string {
+	return fmt.Sprintf("graveyard preflight failed: %s: %v", e.Step, e.Err)
+}
+
+func (e *ErrPreflight) Unwrap() error {
+	return e.Err
+}
+
+// Preflight checks that the graveyard is usable, so that startup can fail
+// early, with a specific reason, instead of coordination failing later:
+//   - EnsureGraveyard: the graveyard exists (or can be created)
+//   - write: a file can be written to the graveyard (ex: not read-only)
+//   - CheckWatchSupport: writes are seen by a watcher
+//   - round trip: a tombstone can be written, read back, and removed
+//
+// The context bounds how long to wait for the watch event.
+func Preflight(ctx context.Context, graveyard string) error {
+	return preflight(ctx, graveyard, Watch)
+}
+
+// preflight runs the Preflight steps, watching with the watch func.
+func preflight(ctx context.Context, graveyard string, watch watchFunc) error {
+	err := EnsureGraveyard(graveyard)
+	if err != nil {
+		return &ErrPreflight{Step: "graveyard", Err: err}
+	}
+	err = checkWritable(graveyard)
+	if err != nil {
+		return &ErrPreflight{Step: "write", Err: err}
+	}
+	err = checkWatchSupport(ctx, graveyard, watch)
+	if err != nil {
+		return &ErrPreflight{Step: "watch", Err: err}
+	}
+	err = checkRoundTrip(graveyard)
+	if err != nil {
+		return &ErrPreflight{Step: "round trip", Err: err}
+	}
+	return nil
+}
+
+// EnsureGraveyard creates the graveyard, if it does not exist, and checks
+// that it is a directory.
+func EnsureGraveyard(graveyard string) error {
+	err := os.MkdirAll(graveyard, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create graveyard: %v", err)
+	}
+	info, err := os.Stat(graveyard)
+	if err != nil {
+		return fmt.Errorf("failed to stat graveyard: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("graveyard is not a directory: %s", graveyard)
+	}
+	return nil
+}
+
+// checkWritable writes a hidden file to the graveyard and removes it.
+func checkWritable(graveyard string) error {
+	path := filepath.Join(graveyard, fmt.Sprintf("%swrite.%d", preflightPrefix, os.Getpid()))
+	err := ioutil.WriteFile(path, []byte{}, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write check file: %v", err)
+	}
+	err = os.Remove(path)
+	if err != nil {
+		return fmt.Errorf("failed to remove check file: %v", err)
+	}
+	return nil
+}
+
+// watchFunc watches a graveyard, like Watch.
+type watchFunc func(ctx context.Context, graveyard string, eventHandler EventHandler, opts ...Option) error
+
+// CheckWatchSupport writes a hidden file to the graveyard and checks that a
+// watcher sees it before the context is done. Watching can fail on some
+// volumes (ex: network file systems) without an error.
+func CheckWatchSupport(ctx context.Context, graveyard string) error {
+	return checkWatchSupport(ctx, graveyard, Watch)
+}
+
+func checkWatchSupport(ctx context.Context, graveyard string, watch watchFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	path := filepath.Join(graveyard, fmt.Sprintf("%swatch.%d", preflightPrefix, os.Getpid()))
+	defer os.Remove(path)
+
+	seen := make(chan struct{})
+	err := watch(ctx, graveyard, func(event fsnotify.Event) {
+		if event.Name == path && event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+			cancel()
+			select {
+			case <-seen:
+			default:
+				close(seen)
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+
+	err = ioutil.WriteFile(path, []byte{}, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write watch check file: %v", err)
+	}
+
+	select {
+	case <-seen:
+		return nil
+	case <-ctx.Done():
+		select {
+		case <-seen:
+			return nil
+		default:
+			return fmt.Errorf("no watch event received: %v", ctx.Err())
+		}
+	}
+}
+
+// checkRoundTrip writes a hidden tombstone, reads it back, and removes it.
+func checkRoundTrip(graveyard string) error {
+	born := time.Now().Truncate(time.Second)
+	ts := &Tombstone{
+		Graveyard: graveyard,
+		Name:      fmt.Sprintf("%stombstone.%d", preflightPrefix, os.Getpid()),
+		Born:      &born,
+	}
+	defer os.Remove(ts.Path())
+
+	err := ts.Write()
+	if err != nil {
+		return err
+	}
+	read, err := Read(graveyard, ts.Name)
+	if err != nil {
+		return err
+	}
+	if read.Born == nil || !read.Born.Equal(born) {
+		return fmt.Errorf("read tombstone does not match written: Born: %v != %v", read.Born, born)
+	}
+	err = os.Remove(ts.Path())
+	if err != nil {
+		return fmt.Errorf("failed to remove tombstone: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPreflight(t *testing.T) {
+	// created, if missing
+	graveyard := filepath.Join(tempGraveyard(t), "graveyard")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Preflight(ctx, graveyard); err != nil {
+		t.Fatalf("expected preflight to pass, got: %v", err)
+	}
+
+	// cleaned up
+	files, err := ioutil.ReadDir(graveyard)
+	if err != nil {
+		t.Fatalf("failed to read graveyard: %v", err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected no files left, got: %d", len(files))
+	}
+}
+
+func TestPreflightNotDirectory(t *testing.T) {
+	graveyard := filepath.Join(tempGraveyard(t), "file")
+	if err := ioutil.WriteFile(graveyard, nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	expectPreflightStep(t, Preflight(context.Background(), graveyard), "graveyard")
+}
+
+// expectPreflightStep fails the test unless the preflight step failed.
+func expectPreflightStep(t *testing.T, err error, step string) {
+	t.Helper()
+	var preflightErr *ErrPreflight
+	if !errors.As(err, &preflightErr) || preflightErr.Step != step {
+		t.Fatalf("expected %s step to fail, got: %v", step, err)
+	}
+	if !errors.Is(err, preflightErr.Err) {
+		t.Fatalf("expected the cause to be unwrapped")
+	}
+}
+
+// skipIfRoot skips the test if permissions can't be denied.
+func skipIfRoot(t *testing.T) {
+	t.Helper()
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+}
+
+// chmod changes the mode of the path, and restores it when the test ends,
+// so the temp dir can be removed.
+func chmod(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatalf("failed to chmod: %v", err)
+	}
+	t.Cleanup(func() {
+		os.Chmod(path, 0755)
+	})
+}
+
+func TestPreflightReadOnly(t *testing.T) {
+	skipIfRoot(t)
+	graveyard := tempGraveyard(t)
+	chmod(t, graveyard, 0555)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	expectPreflightStep(t, Preflight(ctx, graveyard), "write")
+}
+
+func TestPreflightUnwritableParent(t *testing.T) {
+	skipIfRoot(t)
+	parent := tempGraveyard(t)
+	chmod(t, parent, 0555)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	expectPreflightStep(t, Preflight(ctx, filepath.Join(parent, "missing", "graveyard")), "graveyard")
+}
+
+func TestPreflightWatchTimeout(t *testing.T) {
+	// watching succeeds, but no events are delivered (ex: network volume)
+	never := func(context.Context, string, EventHandler, ...Option) error {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	expectPreflightStep(t, preflight(ctx, tempGraveyard(t), never), "watch")
+}
+
+func TestPreflightWatchError(t *testing.T) {
+	// ex: inotify instance limit reached
+	failing := func(context.Context, string, EventHandler, ...Option) error {
+		return errors.New("too many open files")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	expectPreflightStep(t, preflight(ctx, tempGraveyard(t), failing), "watch")
+}
@@ -0,0 +1,125 @@
+package tombstone
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadAll reads all the tombstones in a graveyard, sorted by name.
+// Hidden files (ex: markers) and sidecar files are skipped. If sharded, the
+// graveyard subdirectories are read instead of the top level.
+func ReadAll(graveyard string, opts ...Option) ([]*Tombstone, error) {
+	config := newOptions(opts)
+
+	names, err := listNames(graveyard, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var tombstones []*Tombstone
+	for _, name := range names {
+		ts, err := Read(graveyard, name, withOptions(config))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// removed since listing
+				continue
+			}
+			return nil, err
+		}
+		tombstones = append(tombstones, ts)
+	}
+	return tombstones, nil
+}
+
+// listNames returns the names of all the tombstones in a graveyard, sorted.
+func listNames(graveyard string, config options) ([]string, error) {
+	dirs := []string{graveyard}
+	if config.shard != nil {
+		dirs = nil
+		files, err := ioutil.ReadDir(graveyard)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list graveyard: %v", err)
+		}
+		for _, file := range files {
+			if file.IsDir() && !isHidden(file.Name()) {
+				dirs = append(dirs, filepath.Join(graveyard, file.Name()))
+			}
+		}
+	}
+
+	var names []string
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list graveyard: %v", err)
+		}
+		for _, file := range files {
+			if file.IsDir() || isHidden(file.Name()) || isSidecar(file.Name()) {
+				continue
+			}
+			if file.Mode()&os.ModeSymlink != 0 && !followSymlink(filepath.Join(dir, file.Name()), config) {
+				continue
+			}
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// followSymlink returns true if the symlink should be followed: following
+// is enabled and the link resolves to a file.
+func followSymlink(path string, config options) bool {
+	if !config.symlinks {
+		return false
+	}
+	// Stat follows links and fails on loops
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("Skipping symlink: %v\n", err)
+		return false
+	}
+	return !info.IsDir()
+}
+
+// ForEach reads each tombstone in a graveyard, sorted by name, and calls fn.
+// Iteration stops at the first error, which is returned.
+func ForEach(graveyard string, fn func(*Tombstone) error, opts ...Option) error {
+	tombstones, err := ReadAll(graveyard, opts...)
+	if err != nil {
+		return err
+	}
+	for _, ts := range tombstones {
+		err = fn(ts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// GroupByPodInstance reads all the tombstones in a graveyard and groups them
+// by PodInstanceID, so that each pod lifetime can be analyzed separately.
+// Tombstones without a PodInstanceID are grouped under the empty string.
+func GroupByPodInstance(graveyard string, opts ...Option) (map[string][]*Tombstone, error) {
+	tombstones, err := ReadAll(graveyard, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]*Tombstone{}
+	for _, ts := range tombstones {
+		groups[ts.PodInstanceID] = append(groups[ts.PodInstanceID], ts)
+	}
+	return groups, nil
+}
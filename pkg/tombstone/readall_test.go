@@ -0,0 +1,136 @@
+package tombstone
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGroupByPodInstance(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	for name, id := range map[string]string{"a": "pod-1", "b": "pod-1", "c": "pod-2", "d": ""} {
+		ts := &Tombstone{Graveyard: graveyard, Name: name, PodInstanceID: id}
+		if err := ts.RecordBirth(); err != nil {
+			t.Fatalf("failed to record birth: %v", err)
+		}
+	}
+
+	groups, err := GroupByPodInstance(graveyard)
+	if err != nil {
+		t.Fatalf("failed to group: %v", err)
+	}
+
+	want := map[string][]string{
+		"pod-1": {"a", "b"},
+		"pod-2": {"c"},
+		"":      {"d"},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("expected %d groups, got: %d", len(want), len(groups))
+	}
+	for id, names := range want {
+		var got []string
+		for _, ts := range groups[id] {
+			got = append(got, ts.Name)
+		}
+		sort.Strings(got)
+		if len(got) != len(names) {
+			t.Fatalf("expected group %q to have %v, got: %v", id, names, got)
+		}
+		for i := range names {
+			if got[i] != names[i] {
+				t.Fatalf("expected group %q to have %v, got: %v", id, names, got)
+			}
+		}
+	}
+}
+
+func TestPodInstanceIDRoundTrip(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", PodInstanceID: "pod-1"}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if got := mustRead(t, graveyard, "app"); got.PodInstanceID != "pod-1" {
+		t.Fatalf("expected pod instance id pod-1, got: %q", got.PodInstanceID)
+	}
+}
+
+// symlinkGraveyard returns a graveyard with a real tombstone and links to a
+// tombstone, a missing file, a loop, and a directory.
+func symlinkGraveyard(t *testing.T) string {
+	t.Helper()
+	graveyard := tempGraveyard(t)
+	other := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "real")
+	target := mustRecordBirth(t, other, "target")
+
+	links := map[string]string{
+		"linked":   target.Path(),
+		"dangling": filepath.Join(other, "missing"),
+		"loop":     filepath.Join(graveyard, "loop"),
+		"dir":      other,
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(graveyard, name)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+	return graveyard
+}
+
+func TestReadAllSymlinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		follow bool
+		want   []string
+	}{
+		{name: "skipped by default", follow: false, want: []string{"real"}},
+		{name: "followed", follow: true, want: []string{"linked", "real"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tombstones, err := ReadAll(symlinkGraveyard(t), WithFollowSymlinks(tt.follow))
+			if err != nil {
+				t.Fatalf("failed to read all: %v", err)
+			}
+			var got []string
+			for _, ts := range tombstones {
+				got = append(got, ts.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadFollowsSymlinks(t *testing.T) {
+	// always, without the option
+	if got := mustRead(t, symlinkGraveyard(t), "linked"); got.Born == nil {
+		t.Fatalf("expected linked tombstone to be born, got: %s", got)
+	}
+}
+
+func TestWatchSymlinks(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	target := mustRecordBirth(t, tempGraveyard(t), "target")
+
+	skipped := watchEvents(t, graveyard)
+	followed := watchEvents(t, graveyard, WithFollowSymlinks(true))
+
+	link := filepath.Join(graveyard, "linked")
+	if err := os.Symlink(target.Path(), link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	if event := nextEvent(t, followed); event.Name != link {
+		t.Fatalf("expected event for the link, got: %v", event)
+	}
+	expectNoEvent(t, skipped, 100*time.Millisecond)
+}
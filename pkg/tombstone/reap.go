@@ -0,0 +1,233 @@
+package tombstone
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+)
+
+// CapCount removes the oldest dead tombstones (by Died) until the graveyard
+// contains no more than max tombstones. Alive and unreadable tombstones are
+// never removed, so the graveyard may still exceed max.
+// The names of the removed tombstones are returned.
+func CapCount(graveyard string, max int, opts ...Option) ([]string, error) {
+	config := newOptions(opts)
+
+	names, err := listNames(graveyard, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) <= max {
+		return nil, nil
+	}
+
+	dead := readDead(graveyard, names, config)
+	sortByDeath(dead)
+
+	var removed []string
+	count := len(names)
+	for _, ts := range dead {
+		if count <= max {
+			break
+		}
+		err = removeTombstone(ts)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, ts.Name)
+		count--
+	}
+	return removed, nil
+}
+
+// ReapWhere removes the tombstones for which pred returns true.
+// Tombstones that are not dead (no Died) are never passed to pred, unless
+// WithReapAlive is used, so that a careless predicate can't remove the
+// tombstone of a running process. Unreadable tombstones are skipped.
+// The names of the removed tombstones are returned.
+func ReapWhere(graveyard string, pred func(*Tombstone) bool, opts ...Option) ([]string, error) {
+	config := newOptions(opts)
+
+	names, err := listNames(graveyard, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, name := range names {
+		ts, err := Read(graveyard, name, withOptions(config))
+		if err != nil {
+			continue
+		}
+		if ts.Died == nil && !config.reapAlive {
+			continue
+		}
+		if !pred(ts) {
+			continue
+		}
+		err = removeTombstone(ts)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, ts.Name)
+	}
+	return removed, nil
+}
+
+// WithReapAlive makes ReapWhere pass tombstones that are not dead to the
+// predicate, allowing them to be removed.
+func WithReapAlive(reapAlive bool) Option {
+	return func(c *options) {
+		c.reapAlive = reapAlive
+	}
+}
+
+// readDead reads the named tombstones and returns the dead ones.
+// Unreadable tombstones are skipped.
+func readDead(graveyard string, names []string, config options) []*Tombstone {
+	var dead []*Tombstone
+	for _, name := range names {
+		ts, err := Read(graveyard, name, withOptions(config))
+		if err != nil {
+			continue
+		}
+		if ts.Died != nil {
+			dead = append(dead, ts)
+		}
+	}
+	return dead
+}
+
+// sortByDeath sorts dead tombstones by Died, oldest first.
+func sortByDeath(dead []*Tombstone) {
+	sort.SliceStable(dead, func(i, j int) bool {
+		return dead[i].Died.Before(*dead[j].Died)
+	})
+}
+
+// removeTombstone deletes the tombstone file and heartbeat sidecar file.
+// Already removed tombstones are ignored.
+func removeTombstone(ts *Tombstone) error {
+	log.Printf("Reaping tombstone: %s\n", ts.Path())
+	err := os.Remove(ts.Path())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove tombstone: %v", err)
+	}
+	err = os.Remove(ts.HeartbeatPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove tombstone heartbeat: %v", err)
+	}
+	return nil
+}
+
+// PruneOldGenerations removes the records of older pod generations from the
+// tombstones in a persistent graveyard, keeping only the newest generation
+// of each name, to bound the size of the graveyard and so that stale records
+// don't confuse dependents.
+//
+// A generation is the PodGeneration and PodInstanceID recorded together.
+// Only tombstones in the history log format (see HistoryLog) hold more than
+// one generation per name; other tombstones are never changed. For each
+// name, the newest generation is that of the most recently born record, and
+// the records of other generations are removed. The latest record, which is
+// the tombstone as read, is always kept, so alive tombstones are never
+// removed. Records without a generation are also kept. A tombstone appended
+// to while being pruned is left unchanged.
+// The names of the pruned tombstones are returned.
+func PruneOldGenerations(graveyard string, opts ...Option) ([]string, error) {
+	config := newOptions(opts)
+
+	names, err := listNames(graveyard, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	for _, name := range names {
+		ts := &Tombstone{Graveyard: graveyard, Name: name, Shard: config.shard}
+		ok, err := ts.pruneOldGenerations()
+		if err != nil {
+			return pruned, err
+		}
+		if ok {
+			pruned = append(pruned, name)
+		}
+	}
+	return pruned, nil
+}
+
+// generation identifies the pod generation that wrote a tombstone record.
+type generation struct {
+	podGeneration string
+	podInstanceID string
+}
+
+func generationOf(ts *Tombstone) generation {
+	return generation{podGeneration: ts.PodGeneration, podInstanceID: ts.PodInstanceID}
+}
+
+// pruneOldGenerations rewrites the history log file with only the records
+// of the newest generation, and returns true if any were removed.
+// Unreadable tombstones are skipped.
+func (t *Tombstone) pruneOldGenerations() (bool, error) {
+	data, err := ioutil.ReadFile(t.Path())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read tombstone file: %v", err)
+	}
+	if !isHistoryLog(data) {
+		return false, nil
+	}
+
+	records := historyRecords(data)
+	generations := make([]generation, len(records))
+	var newest *Tombstone
+	for i, record := range records {
+		ts := &Tombstone{}
+		err = json.Unmarshal(record, ts)
+		if err != nil {
+			return false, nil
+		}
+		generations[i] = generationOf(ts)
+		if ts.Born == nil || generations[i] == (generation{}) {
+			continue
+		}
+		if newest == nil || ts.Born.After(*newest.Born) {
+			newest = ts
+		}
+	}
+	if newest == nil {
+		return false, nil
+	}
+
+	var kept [][]byte
+	for i, record := range records {
+		if i == len(records)-1 || generations[i] == (generation{}) || generations[i] == generationOf(newest) {
+			kept = append(kept, record)
+		}
+	}
+	if len(kept) == len(records) {
+		return false, nil
+	}
+
+	// re-check, in case a record was appended in the meantime
+	latest, err := ioutil.ReadFile(t.Path())
+	if err != nil || !bytes.Equal(latest, data) {
+		return false, nil
+	}
+
+	log.Printf("Pruning old generations: %s\n", t.Path())
+	t.History = &HistoryLog{MaxRecords: len(kept)}
+	err = t.writeHistory(kept)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,239 @@
+package tombstone
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// mustRecordDeaths records deaths for the names, in order, a few
+// milliseconds apart, so they sort by death.
+func mustRecordDeaths(t *testing.T, graveyard string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		mustRecordDeath(t, graveyard, name, 0)
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// remainingNames returns the sorted names of the tombstones in the graveyard.
+func remainingNames(t *testing.T, graveyard string, opts ...Option) []string {
+	t.Helper()
+	names, err := listNames(graveyard, newOptions(opts))
+	if err != nil {
+		t.Fatalf("failed to list tombstones: %v", err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCapCount(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeaths(t, graveyard, "first", "second", "third")
+	mustRecordBirth(t, graveyard, "alive")
+
+	removed, err := CapCount(graveyard, 2)
+	if err != nil {
+		t.Fatalf("failed to cap count: %v", err)
+	}
+	// oldest death first
+	if !reflect.DeepEqual(removed, []string{"first", "second"}) {
+		t.Fatalf("expected [first second] removed, got: %v", removed)
+	}
+	if got := remainingNames(t, graveyard); !reflect.DeepEqual(got, []string{"alive", "third"}) {
+		t.Fatalf("expected [alive third] remaining, got: %v", got)
+	}
+}
+
+func TestCapCountUnderMax(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeaths(t, graveyard, "a", "b")
+
+	removed, err := CapCount(graveyard, 2)
+	if err != nil {
+		t.Fatalf("failed to cap count: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("expected nothing removed, got: %v", removed)
+	}
+}
+
+func TestCapCountKeepsAlive(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeaths(t, graveyard, "dead")
+	mustRecordBirth(t, graveyard, "a")
+	mustRecordBirth(t, graveyard, "b")
+
+	removed, err := CapCount(graveyard, 0)
+	if err != nil {
+		t.Fatalf("failed to cap count: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"dead"}) {
+		t.Fatalf("expected only [dead] removed, got: %v", removed)
+	}
+	if got := remainingNames(t, graveyard); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("expected alive tombstones to exceed max, got: %v", got)
+	}
+}
+
+func TestReapWhere(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "ok", 0)
+	mustRecordDeath(t, graveyard, "failed", 1)
+	alive := mustRecordBirth(t, graveyard, "alive")
+	alive.Status = "failed"
+	if err := alive.Write(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	var seen []string
+	removed, err := ReapWhere(graveyard, func(ts *Tombstone) bool {
+		seen = append(seen, ts.Name)
+		return ts.Status == "failed" || (ts.ExitCode != nil && *ts.ExitCode != 0)
+	})
+	if err != nil {
+		t.Fatalf("failed to reap: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"failed"}) {
+		t.Fatalf("expected [failed] removed, got: %v", removed)
+	}
+	// alive tombstones are never passed to the predicate
+	if !reflect.DeepEqual(seen, []string{"failed", "ok"}) {
+		t.Fatalf("expected only dead tombstones passed to predicate, got: %v", seen)
+	}
+	if got := remainingNames(t, graveyard); !reflect.DeepEqual(got, []string{"alive", "ok"}) {
+		t.Fatalf("expected [alive ok] remaining, got: %v", got)
+	}
+}
+
+func TestReapWhereAlive(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "alive")
+	mustRecordDeath(t, graveyard, "dead", 0)
+
+	removed, err := ReapWhere(graveyard, func(ts *Tombstone) bool {
+		return ts.Died == nil
+	}, WithReapAlive(true))
+	if err != nil {
+		t.Fatalf("failed to reap: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"alive"}) {
+		t.Fatalf("expected [alive] removed, got: %v", removed)
+	}
+}
+
+// recordGeneration records a birth and, if dead, a death in the history log
+// of a new tombstone from the pod generation, as a restarted pod would.
+func recordGeneration(t *testing.T, graveyard, name, podGeneration, podInstanceID string, dead bool) {
+	t.Helper()
+	ts := &Tombstone{
+		Graveyard:     graveyard,
+		Name:          name,
+		History:       &HistoryLog{},
+		PodGeneration: podGeneration,
+		PodInstanceID: podInstanceID,
+	}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if dead {
+		if err := ts.RecordDeath(0); err != nil {
+			t.Fatalf("failed to record death: %v", err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+}
+
+// generations returns the pod generation of each history record.
+func generations(t *testing.T, graveyard, name string) []string {
+	t.Helper()
+	var gens []string
+	for _, record := range mustReadHistory(t, graveyard, name) {
+		gens = append(gens, record.PodGeneration+"/"+record.PodInstanceID)
+	}
+	return gens
+}
+
+func TestPruneOldGenerations(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	recordGeneration(t, graveyard, "app", "1", "pod-a", true)
+	recordGeneration(t, graveyard, "app", "2", "pod-a", true)
+	recordGeneration(t, graveyard, "app", "2", "pod-b", true)
+	recordGeneration(t, graveyard, "db", "1", "pod-a", true)
+	// alive
+	recordGeneration(t, graveyard, "db", "2", "pod-b", false)
+	// one generation
+	recordGeneration(t, graveyard, "cache", "1", "pod-a", true)
+	// other generations in other names are not compared
+	mustRecordDeath(t, graveyard, "plain", 0)
+
+	pruned, err := PruneOldGenerations(graveyard)
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	sort.Strings(pruned)
+	if want := []string{"app", "db"}; !reflect.DeepEqual(pruned, want) {
+		t.Fatalf("expected %v pruned, got: %v", want, pruned)
+	}
+
+	tests := map[string][]string{
+		"app":   {"2/pod-b", "2/pod-b"},
+		"db":    {"2/pod-b"},
+		"cache": {"1/pod-a", "1/pod-a"},
+		"plain": {"/"},
+	}
+	for name, want := range tests {
+		if got := generations(t, graveyard, name); !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %s generations %v, got: %v", name, want, got)
+		}
+	}
+	if got := mustRead(t, graveyard, "db"); got.Born == nil || got.Died != nil {
+		t.Fatalf("expected alive tombstone to be kept, got: %s", got)
+	}
+	if got := remainingNames(t, graveyard); !reflect.DeepEqual(got, []string{"app", "cache", "db", "plain"}) {
+		t.Fatalf("expected no tombstones removed, got: %v", got)
+	}
+}
+
+func TestPruneOldGenerationsKeepsLatest(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	recordGeneration(t, graveyard, "app", "1", "", true)
+	recordGeneration(t, graveyard, "app", "", "", true)
+	recordGeneration(t, graveyard, "app", "2", "", false)
+
+	// the older generation is presumed dead, after the newer was born
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", History: &HistoryLog{}, PodGeneration: "1"}
+	if err := ts.RecordDeath(1); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	if _, err := PruneOldGenerations(graveyard); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	// records without a generation are kept, and the latest is kept
+	want := []string{"/", "/", "2/", "1/"}
+	if got := generations(t, graveyard, "app"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected generations %v, got: %v", want, got)
+	}
+	if got := mustRead(t, graveyard, "app"); got.ExitCode == nil || *got.ExitCode != 1 {
+		t.Fatalf("expected the latest record to be read, got: %s", got)
+	}
+}
+
+func TestPruneOldGenerationsWithoutIDs(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	recordGeneration(t, graveyard, "app", "", "", true)
+	recordGeneration(t, graveyard, "app", "", "", true)
+
+	pruned, err := PruneOldGenerations(graveyard)
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if len(pruned) != 0 {
+		t.Fatalf("expected none pruned, got: %v", pruned)
+	}
+	if got := mustReadHistory(t, graveyard, "app"); len(got) != 4 {
+		t.Fatalf("expected 4 records, got: %d", len(got))
+	}
+}
@@ -0,0 +1,66 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WaitForRemoval blocks until the named tombstone file no longer exists, or
+// the context is done. If it doesn't exist already, WaitForRemoval returns
+// immediately. Atomic re-writes (a temp file renamed over the tombstone) are
+// not removals, because the tombstone still exists afterwards.
+func WaitForRemoval(ctx context.Context, graveyard, name string, opts ...Option) error {
+	config := newOptions(opts)
+	path := (&Tombstone{
+		Graveyard: graveyard,
+		Name:      name,
+		Shard:     config.shard,
+	}).Path()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	removed := make(chan struct{})
+	check := func() {
+		_, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			once.Do(func() {
+				close(removed)
+			})
+			cancel()
+		}
+	}
+
+	// watch before checking, so no changes are missed
+	err := Watch(ctx, filepath.Dir(path), func(event fsnotify.Event) {
+		if event.Name != path || event.Op&(fsnotify.Remove|fsnotify.Rename) == 0 {
+			return
+		}
+		check()
+	})
+	if err != nil {
+		_, statErr := os.Stat(filepath.Dir(path))
+		if errors.Is(statErr, os.ErrNotExist) {
+			// no graveyard, so no tombstone
+			return nil
+		}
+		return fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+	check()
+
+	<-ctx.Done()
+
+	select {
+	case <-removed:
+		return nil
+	default:
+		return fmt.Errorf("waiting for removal of %s: %v", name, ctx.Err())
+	}
+}
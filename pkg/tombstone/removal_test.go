@@ -0,0 +1,63 @@
+package tombstone
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitForRemoval(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := mustRecordDeath(t, graveyard, "app", 0)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if err := os.Remove(ts.Path()); err != nil {
+			t.Errorf("failed to remove: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := WaitForRemoval(ctx, graveyard, "app"); err != nil {
+		t.Fatalf("expected removal, got: %v", err)
+	}
+}
+
+func TestWaitForRemovalMissing(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := WaitForRemoval(ctx, graveyard, "missing"); err != nil {
+		t.Fatalf("expected missing tombstone to be removed already, got: %v", err)
+	}
+	if err := WaitForRemoval(ctx, filepath.Join(graveyard, "missing"), "app"); err != nil {
+		t.Fatalf("expected missing graveyard to have no tombstone, got: %v", err)
+	}
+}
+
+func TestWaitForRemovalIgnoresAtomicRewrite(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := mustRecordBirth(t, graveyard, "app")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		pretty, err := ts.marshal()
+		if err != nil {
+			t.Errorf("failed to marshal: %v", err)
+			return
+		}
+		if err := writeFileAtomic(ts.Path(), pretty); err != nil {
+			t.Errorf("failed to rewrite: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := WaitForRemoval(ctx, graveyard, "app"); err == nil {
+		t.Fatalf("expected timeout, because the tombstone still exists")
+	}
+}
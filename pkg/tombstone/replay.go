@@ -0,0 +1,84 @@
+package tombstone
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxReplayLine is the longest event line that Replay will read.
+const maxReplayLine = 1024 * 1024
+
+// Replay reads an event log of JSON lines (ex: from the tombstone Mirror or
+// FollowAll with FormatJSONL) and applies the events, in order, to the
+// graveyard, to restore the tombstones (ex: after losing the volume).
+// Each event replaces the whole tombstone. Remove events delete it.
+// Lines that aren't JSON objects, or are from other sources (ex: the
+// supervised process output), are ignored. Malformed lines are skipped and
+// returned together as an error, after the rest of the log is applied.
+func Replay(graveyard string, r io.Reader, opts ...Option) error {
+	config := newOptions(opts)
+
+	var malformed []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReplayLine)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			// not json, so not an event
+			continue
+		}
+
+		var event MirrorEvent
+		err := json.Unmarshal([]byte(line), &event)
+		if err != nil {
+			malformed = append(malformed, fmt.Sprintf("line %d: %v", lineNum, err))
+			continue
+		}
+		if event.Source != MirrorSource {
+			continue
+		}
+		if event.Name == "" || strings.ContainsRune(event.Name, '/') {
+			malformed = append(malformed, fmt.Sprintf("line %d: invalid name: %q", lineNum, event.Name))
+			continue
+		}
+
+		if event.Event == EventRemove {
+			err = removeTombstone(&Tombstone{
+				Graveyard: graveyard,
+				Name:      event.Name,
+				Shard:     config.shard,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if event.Tombstone == nil {
+			malformed = append(malformed, fmt.Sprintf("line %d: missing tombstone", lineNum))
+			continue
+		}
+
+		ts := event.Tombstone
+		ts.Graveyard = graveyard
+		ts.Name = event.Name
+		ts.Shard = config.shard
+		err = ts.Write()
+		if err != nil {
+			return err
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read event log: %v", err)
+	}
+
+	if len(malformed) > 0 {
+		return fmt.Errorf("skipped %d malformed event(s): %s", len(malformed), strings.Join(malformed, "; "))
+	}
+	return nil
+}
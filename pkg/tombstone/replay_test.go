@@ -0,0 +1,71 @@
+package tombstone
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReplayMirrorLog(t *testing.T) {
+	// the mirror shares stdout with the supervised process
+	var eventLog bytes.Buffer
+	eventLog.WriteString("app starting\n")
+	app := &Tombstone{Graveyard: tempGraveyard(t), Name: "app", Mirror: &eventLog}
+	if err := app.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	eventLog.WriteString("{\"msg\": \"app json eventLog\"}\n")
+	if err := app.RecordDeath(3); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	graveyard := tempGraveyard(t)
+	if err := Replay(graveyard, &eventLog); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.Born == nil || !got.Born.Equal(*app.Born) || got.ExitCode == nil || *got.ExitCode != 3 {
+		t.Fatalf("expected restored death with exit code 3, got: %s", got)
+	}
+}
+
+func TestReplayRemove(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	eventLog := strings.Join([]string{
+		`{"Source":"kubexit","Event":"birth","Name":"app","Tombstone":{"Born":"2020-01-02T03:04:05Z"}}`,
+		`{"Source":"kubexit","Event":"remove","Name":"app"}`,
+		`{"Source":"kubexit","Event":"birth","Name":"db","Tombstone":{"Born":"2020-01-02T03:04:05Z"}}`,
+	}, "\n")
+	if err := Replay(graveyard, strings.NewReader(eventLog)); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if got := remainingNames(t, graveyard); len(got) != 1 || got[0] != "db" {
+		t.Fatalf("expected only db, got: %v", got)
+	}
+}
+
+func TestReplayMalformed(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	eventLog := strings.Join([]string{
+		`{"Source":"kubexit","Event":"birth","Name":"a",`,
+		`{"Source":"kubexit","Event":"birth","Name":"../escape","Tombstone":{}}`,
+		`{"Source":"kubexit","Event":"birth","Name":"b"}`,
+		`{"Source":"other","Event":"birth","Name":"c","Tombstone":{}}`,
+		`{"Source":"kubexit","Event":"birth","Name":"d","Tombstone":{"Born":"2020-01-02T03:04:05Z"}}`,
+	}, "\n")
+
+	err := Replay(graveyard, strings.NewReader(eventLog))
+	if err == nil {
+		t.Fatalf("expected malformed lines to be returned")
+	}
+	for _, want := range []string{"skipped 3 malformed", "line 1:", "line 2: invalid name", "line 3: missing tombstone"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to contain %q, got: %v", want, err)
+		}
+	}
+	// the rest of the eventLog is applied, other sources are ignored
+	if got := remainingNames(t, graveyard); len(got) != 1 || got[0] != "d" {
+		t.Fatalf("expected only d, got: %v", got)
+	}
+}
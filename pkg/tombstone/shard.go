@@ -0,0 +1,79 @@
+package tombstone
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ShardFunc maps a tombstone name to a graveyard subdirectory, to spread
+// tombstones across directories in dense graveyards.
+// All readers, writers, and watchers of a graveyard must use the same ShardFunc.
+type ShardFunc func(name string) string
+
+// HashShard maps a name to the first two hex characters of its SHA-1 hash,
+// the same layout git uses for objects.
+func HashShard(name string) string {
+	sum := sha1.Sum([]byte(name))
+	return hex.EncodeToString(sum[:1])
+}
+
+// WithShard reads and watches tombstones in the subdirectories chosen by the
+// ShardFunc, instead of the top level of the graveyard.
+func WithShard(shard ShardFunc) Option {
+	return func(c *options) {
+		c.shard = shard
+	}
+}
+
+// addShardDirs watches all existing subdirectories of the graveyard.
+func addShardDirs(watcher *fsnotify.Watcher, graveyard string) error {
+	files, err := ioutil.ReadDir(graveyard)
+	if err != nil {
+		return fmt.Errorf("failed to list graveyard: %v", err)
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		err = watcher.Add(filepath.Join(graveyard, file.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to add shard watcher: %v", err)
+		}
+	}
+	return nil
+}
+
+// addShardDir watches a new shard subdirectory and returns synthetic create
+// events for any files created in it before it was watched.
+// Returns false if the path is not a directory.
+func addShardDir(watcher *fsnotify.Watcher, path string) (bool, []fsnotify.Event, error) {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false, nil, nil
+	}
+	err = watcher.Add(path)
+	if err != nil {
+		return true, nil, fmt.Errorf("failed to add shard watcher: %v", err)
+	}
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return true, nil, fmt.Errorf("failed to list shard: %v", err)
+	}
+	var events []fsnotify.Event
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		events = append(events, fsnotify.Event{
+			Name: filepath.Join(path, file.Name()),
+			Op:   fsnotify.Create,
+		})
+	}
+	return true, events, nil
+}
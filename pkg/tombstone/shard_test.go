@@ -0,0 +1,89 @@
+package tombstone
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashShard(t *testing.T) {
+	// sha1("app") = 7d1043...
+	if got := HashShard("app"); got != "7d" {
+		t.Fatalf("expected shard 7d, got: %s", got)
+	}
+	if HashShard("app") != HashShard("app") {
+		t.Fatalf("expected shard to be deterministic")
+	}
+}
+
+func TestShardedWriteAndRead(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", Shard: HashShard}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	path := filepath.Join(graveyard, HashShard("app"), "app")
+	if ts.Path() != path {
+		t.Fatalf("expected path %s, got: %s", path, ts.Path())
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected tombstone in shard dir: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app", WithShard(HashShard))
+	if got.Born == nil {
+		t.Fatalf("expected sharded tombstone to be born")
+	}
+	if _, err := Read(graveyard, "app"); err == nil {
+		t.Fatalf("expected unsharded read to miss the sharded tombstone")
+	}
+}
+
+func TestShardedReadAll(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	names := []string{"a", "b", "c", "d"}
+	for _, name := range names {
+		ts := &Tombstone{Graveyard: graveyard, Name: name, Shard: HashShard}
+		if err := ts.RecordBirth(); err != nil {
+			t.Fatalf("failed to record birth: %v", err)
+		}
+	}
+
+	tombstones, err := ReadAll(graveyard, WithShard(HashShard))
+	if err != nil {
+		t.Fatalf("failed to read all: %v", err)
+	}
+	if len(tombstones) != len(names) {
+		t.Fatalf("expected %d tombstones, got: %d", len(names), len(tombstones))
+	}
+	for i, ts := range tombstones {
+		if ts.Name != names[i] {
+			t.Errorf("expected tombstone %d to be %s, sorted by name, got: %s", i, names[i], ts.Name)
+		}
+	}
+}
+
+func TestShardedWatchNewShardDir(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		// creates the shard dir and the tombstone in quick succession
+		ts := &Tombstone{Graveyard: graveyard, Name: "app", Shard: HashShard}
+		if err := ts.RecordBirth(); err != nil {
+			t.Errorf("failed to record birth: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := WaitForBirth(ctx, graveyard, "app", WithShard(HashShard))
+	if err != nil {
+		t.Fatalf("expected birth in new shard dir to be seen, got: %v", err)
+	}
+}
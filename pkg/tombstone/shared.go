@@ -0,0 +1,111 @@
+package tombstone
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// sharedMarkerPrefix prefixes the hidden marker files written by VerifyShared.
+const sharedMarkerPrefix = ".shared."
+
+// ErrGraveyardNotShared is returned by VerifyShared when no sibling was seen.
+type ErrGraveyardNotShared struct {
+	Graveyard string
+}
+
+func (e *ErrGraveyardNotShared) Error() string {
+	return fmt.Sprintf("no sibling files seen in graveyard %s: it may not be shared between containers", e.Graveyard)
+}
+
+// VerifyShared checks that the graveyard is shared with at least one other
+// container. It writes a hidden marker file for the named container and then
+// waits until a file from any sibling (tombstone or marker) appears, or the
+// context is done, in which case ErrGraveyardNotShared is returned. Hidden
+// files and heartbeat sidecars, which may be our own, don't count.
+//
+// The check is a heuristic. A graveyard can't be proven to be isolated, only
+// seen to be shared:
+//   - A pod with only one kubexit container will always fail the check.
+//   - Siblings that start later than the context deadline will be missed.
+//   - Markers and tombstones left in a persistent graveyard by previous runs
+//     will pass the check, even if the current mount is isolated.
+func VerifyShared(ctx context.Context, graveyard, name string) error {
+	err := os.MkdirAll(graveyard, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create graveyard: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	isSibling := func(fileName string) bool {
+		if fileName == name || fileName == sharedMarkerPrefix+name {
+			return false
+		}
+		if strings.HasPrefix(fileName, sharedMarkerPrefix) {
+			return true
+		}
+		// other hidden files (ex: atomic write temp files) and sidecars
+		// (ex: heartbeats) may be our own
+		return !isHidden(fileName) && !isSidecar(fileName)
+	}
+
+	found := make(chan string, 1)
+	onFound := func(fileName string) {
+		select {
+		case found <- fileName:
+		default:
+		}
+		cancel()
+	}
+
+	// watch before writing the marker, so no siblings are missed
+	err = Watch(ctx, graveyard, func(event fsnotify.Event) {
+		if event.Op&fsnotify.Create != fsnotify.Create {
+			return
+		}
+		fileName := filepath.Base(event.Name)
+		if isSibling(fileName) {
+			onFound(fileName)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+
+	markerPath := filepath.Join(graveyard, sharedMarkerPrefix+name)
+	err = ioutil.WriteFile(markerPath, []byte{}, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write shared marker: %v", err)
+	}
+
+	files, err := ioutil.ReadDir(graveyard)
+	if err != nil {
+		return fmt.Errorf("failed to list graveyard: %v", err)
+	}
+	for _, file := range files {
+		if isSibling(file.Name()) {
+			onFound(file.Name())
+			break
+		}
+	}
+
+	<-ctx.Done()
+
+	select {
+	case fileName := <-found:
+		log.Printf("Graveyard shared with sibling: %s\n", strings.TrimPrefix(fileName, sharedMarkerPrefix))
+		return nil
+	default:
+		return &ErrGraveyardNotShared{
+			Graveyard: graveyard,
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerifySharedSiblingExists(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "sidecar")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := VerifyShared(ctx, graveyard, "app")
+	if err != nil {
+		t.Fatalf("expected existing sibling to pass, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(graveyard, sharedMarkerPrefix+"app")); err != nil {
+		t.Fatalf("expected shared marker to be written: %v", err)
+	}
+}
+
+func TestVerifySharedSiblingLater(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	recordBirthLater(t, graveyard, "sidecar", 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := VerifyShared(ctx, graveyard, "app")
+	if err != nil {
+		t.Fatalf("expected later sibling to pass, got: %v", err)
+	}
+}
+
+func TestVerifySharedNotShared(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	// our own files don't count
+	mustRecordBirth(t, graveyard, "app")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := VerifyShared(ctx, graveyard, "app")
+	var notShared *ErrGraveyardNotShared
+	if !errors.As(err, &notShared) {
+		t.Fatalf("expected ErrGraveyardNotShared, got: %v", err)
+	}
+	if notShared.Graveyard != graveyard {
+		t.Errorf("expected graveyard %s, got: %s", graveyard, notShared.Graveyard)
+	}
+}
+
+func TestVerifySharedNotSharedWhileWriting(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", HeartbeatSidecar: true}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	// our own heartbeats and atomic rewrites don't count
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := ts.Heartbeat(); err != nil {
+				t.Errorf("failed to heartbeat: %v", err)
+			}
+			if err := ts.Write(); err != nil {
+				t.Errorf("failed to write: %v", err)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := VerifyShared(ctx, graveyard, "app")
+	var notShared *ErrGraveyardNotShared
+	if !errors.As(err, &notShared) {
+		t.Fatalf("expected ErrGraveyardNotShared, got: %v", err)
+	}
+	if _, err := os.Stat(ts.HeartbeatPath()); err != nil {
+		t.Fatalf("expected heartbeat sidecar to be written: %v", err)
+	}
+}
+
+func TestVerifySharedSiblingMarker(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	// two containers verifying at the same time see each other's markers
+	errs := make(chan error, 2)
+	for _, name := range []string{"a", "b"} {
+		go func(name string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			errs <- VerifyShared(ctx, graveyard, name)
+		}(name)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("expected sibling markers to pass, got: %v", err)
+		}
+	}
+}
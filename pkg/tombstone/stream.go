@@ -0,0 +1,206 @@
+package tombstone
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ColorMode defines when StreamToWriter colors its output.
+type ColorMode int
+
+const (
+	// ColorAuto colors output if the writer is a terminal.
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+// DefaultStreamPrefix prefixes lines written by StreamToWriter, if
+// StreamOptions.Prefix is empty.
+const DefaultStreamPrefix = "[kubexit] "
+
+// ANSI color codes, by transition.
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// StreamOptions configures StreamToWriter.
+type StreamOptions struct {
+	// Prefix is written at the start of each line.
+	// Default: DefaultStreamPrefix.
+	Prefix string
+	// Color is when to color the transitions.
+	Color ColorMode
+	// Include is which transitions to write: EventBirth, EventReady,
+	// EventTerminating, or EventDeath. Default: all.
+	Include []EventType
+}
+
+// StreamToWriter writes a line for each lifecycle transition of each
+// tombstone in the graveyard to w (ex: stderr), with the transition and
+// the tombstone Summary, until the context is done, so that coordination
+// can be followed inline with the app logs. Existing tombstones are not
+// written, only their later transitions.
+func StreamToWriter(ctx context.Context, graveyard string, w io.Writer, opts StreamOptions, watchOpts ...Option) error {
+	config := newOptions(watchOpts)
+
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = DefaultStreamPrefix
+	}
+	color := opts.Color == ColorAlways || (opts.Color == ColorAuto && isTerminal(w))
+	include := map[EventType]bool{}
+	for _, event := range opts.Include {
+		include[event] = true
+	}
+
+	// read before watching, so existing tombstones aren't written
+	tombstones, err := ReadAll(graveyard, withOptions(config))
+	if err != nil {
+		return err
+	}
+	var lock sync.Mutex
+	prev := map[string]*Tombstone{}
+	for _, ts := range tombstones {
+		prev[ts.Name] = ts
+	}
+
+	write := func(ts *Tombstone, transition EventType) {
+		if len(include) > 0 && !include[transition] {
+			return
+		}
+		label := string(transition)
+		if color {
+			label = transitionColor(ts, transition) + label + colorReset
+		}
+		_, err := fmt.Fprintf(w, "%s%s %s\n", prefix, label, ts.Summary())
+		if err != nil {
+			log.Printf("Error: failed to write tombstone transition: %v\n", err)
+		}
+	}
+
+	// handle a change. The lock must be held.
+	handle := func(event EventType, name string, ts *Tombstone) {
+		if event == EventRemove {
+			delete(prev, name)
+			return
+		}
+		if isStale(prev[name], ts) {
+			return
+		}
+		for _, transition := range transitions(prev[name], ts) {
+			write(ts, transition)
+		}
+		prev[name] = ts
+	}
+	handler := func(event EventType, name string, ts *Tombstone) {
+		lock.Lock()
+		defer lock.Unlock()
+		handle(event, name, ts)
+	}
+
+	err = Watch(ctx, graveyard, readingHandler(graveyard, config, handler), withOptions(config))
+	if err != nil {
+		return fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+
+	// read again after watching, so no changes are missed, while holding
+	// the lock, so changes seen by the watcher can't interleave
+	lock.Lock()
+	tombstones, err = ReadAll(graveyard, withOptions(config))
+	if err == nil {
+		for _, ts := range tombstones {
+			handle(EventWrite, ts.Name, ts)
+		}
+	}
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// transitions returns the lifecycle transitions from prev (nil if new) to
+// next, in lifecycle order. A phase transitions when its timestamp is
+// recorded or changes, so that rebirth after a restart is a new birth.
+func transitions(prev, next *Tombstone) []EventType {
+	if prev == nil {
+		prev = &Tombstone{}
+	}
+	var events []EventType
+	if changed(prev.Born, next.Born) {
+		events = append(events, EventBirth)
+	}
+	if changed(prev.Ready, next.Ready) {
+		events = append(events, EventReady)
+	}
+	if changed(prev.Terminating, next.Terminating) {
+		events = append(events, EventTerminating)
+	}
+	if changed(prev.Died, next.Died) {
+		events = append(events, EventDeath)
+	}
+	return events
+}
+
+// lastChanged returns the latest timestamp recorded in the tombstone. Each
+// write records the time it happened (ex: a birth, heartbeat, or death), so
+// this orders the snapshots of a tombstone.
+func lastChanged(ts *Tombstone) time.Time {
+	var last time.Time
+	for _, t := range []*time.Time{ts.Born, ts.Ready, ts.LastHeartbeat, ts.Terminating, ts.Died} {
+		if t != nil && t.After(last) {
+			last = *t
+		}
+	}
+	return last
+}
+
+// isStale returns true if next is an older snapshot than prev (ex: read
+// before a change that was already handled), so it can be skipped, instead
+// of its changes being seen again.
+func isStale(prev, next *Tombstone) bool {
+	return prev != nil && lastChanged(next).Before(lastChanged(prev))
+}
+
+// changed returns true if next is recorded and differs from prev.
+func changed(prev, next *time.Time) bool {
+	return next != nil && (prev == nil || !prev.Equal(*next))
+}
+
+func transitionColor(ts *Tombstone, transition EventType) string {
+	switch transition {
+	case EventBirth:
+		return colorGreen
+	case EventReady:
+		return colorCyan
+	case EventTerminating:
+		return colorYellow
+	default:
+		if ts.Succeeded(ts.SuccessCodes) {
+			return colorGreen
+		}
+		return colorRed
+	}
+}
+
+// isTerminal returns true if the writer is a character device.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,168 @@
+package tombstone
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stream runs StreamToWriter until the test ends, and waits for it to be
+// watching, by recording the birth of a probe tombstone until it's written.
+func stream(t *testing.T, graveyard string, opts StreamOptions) *syncBuffer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	out := &syncBuffer{}
+	go func() {
+		defer close(done)
+		if err := StreamToWriter(ctx, graveyard, out, opts); err != nil {
+			t.Errorf("failed to stream: %v", err)
+		}
+	}()
+
+	probe := &Tombstone{Graveyard: graveyard, Name: "probe"}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), " probe ") {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for stream, got:\n%s", out.String())
+		}
+		if err := probe.RecordBirth(); err != nil {
+			t.Fatalf("failed to record birth: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return out
+}
+
+// linesWith returns the lines containing the substring.
+func linesWith(out *syncBuffer, substr string) []string {
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.Contains(line, substr) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestStreamToWriter(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "existing", 0)
+
+	out := stream(t, graveyard, StreamOptions{Color: ColorNever})
+
+	ts := mustRecordBirth(t, graveyard, "app")
+	awaitLine(t, out, "[kubexit] birth app Running")
+	if err := ts.RecordReady(); err != nil {
+		t.Fatalf("failed to record ready: %v", err)
+	}
+	awaitLine(t, out, "[kubexit] ready app")
+	if err := ts.RecordDeath(2); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	awaitLine(t, out, "[kubexit] death app Failed exit=2")
+
+	// existing tombstones are not written
+	if lines := linesWith(out, " existing "); len(lines) > 0 {
+		t.Fatalf("expected no lines for existing tombstone, got: %q", lines)
+	}
+}
+
+func TestStreamToWriterRebirth(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	out := stream(t, graveyard, StreamOptions{Prefix: "> ", Color: ColorNever})
+
+	ts := mustRecordBirth(t, graveyard, "app")
+	awaitLine(t, out, "> birth app")
+	time.Sleep(10 * time.Millisecond)
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(linesWith(out, "> birth app")) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for rebirth, got:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamToWriterInclude(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	out := stream(t, graveyard, StreamOptions{
+		Color:   ColorNever,
+		Include: []EventType{EventBirth, EventDeath},
+	})
+
+	ts := mustRecordBirth(t, graveyard, "app")
+	if err := ts.RecordReady(); err != nil {
+		t.Fatalf("failed to record ready: %v", err)
+	}
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	awaitLine(t, out, "death app Succeeded")
+
+	if lines := linesWith(out, "ready app"); len(lines) > 0 {
+		t.Fatalf("expected ready to be excluded, got: %q", lines)
+	}
+	if lines := linesWith(out, "birth app"); len(lines) != 1 {
+		t.Fatalf("expected one birth line, got: %q", lines)
+	}
+}
+
+func TestStreamToWriterColor(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	out := stream(t, graveyard, StreamOptions{Color: ColorAlways})
+
+	ts := mustRecordBirth(t, graveyard, "app")
+	awaitLine(t, out, colorGreen+"birth"+colorReset+" app")
+	if err := ts.RecordDeath(1); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	awaitLine(t, out, colorRed+"death"+colorReset+" app")
+}
+
+func TestStreamToWriterColorAuto(t *testing.T) {
+	// not a terminal
+	if isTerminal(&syncBuffer{}) {
+		t.Fatal("expected a buffer not to be a terminal")
+	}
+	graveyard := tempGraveyard(t)
+	out := stream(t, graveyard, StreamOptions{})
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Fatalf("expected no color, got: %q", out.String())
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	born := time.Now()
+	later := born.Add(time.Second)
+
+	tests := []struct {
+		name       string
+		prev, next *Tombstone
+		want       bool
+	}{
+		{name: "new", prev: nil, next: &Tombstone{Born: &born}, want: false},
+		{name: "same", prev: &Tombstone{Born: &born}, next: &Tombstone{Born: &born}, want: false},
+		{name: "death", prev: &Tombstone{Born: &born}, next: &Tombstone{Born: &born, Died: &later}, want: false},
+		{name: "rebirth", prev: &Tombstone{Born: &born, Died: &born}, next: &Tombstone{Born: &later}, want: false},
+		{name: "before death", prev: &Tombstone{Born: &born, Died: &later}, next: &Tombstone{Born: &born}, want: true},
+		{name: "before heartbeat", prev: &Tombstone{Born: &born, LastHeartbeat: &later}, next: &Tombstone{Born: &born}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStale(tt.prev, tt.next); got != tt.want {
+				t.Fatalf("expected stale %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
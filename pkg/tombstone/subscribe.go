@@ -0,0 +1,136 @@
+package tombstone
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultSubscribeBuffer is the channel capacity used when
+// SubscribeOptions.Buffer is not positive.
+const DefaultSubscribeBuffer = 16
+
+// OverflowPolicy defines what happens when a subscriber's buffer is full.
+type OverflowPolicy int
+
+const (
+	// Block stalls the watch loop until the subscriber catches up.
+	// No events are dropped.
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest buffered event to make room.
+	DropOldest
+	// DropNewest discards the new event.
+	DropNewest
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Block:
+		return "Block"
+	case DropOldest:
+		return "DropOldest"
+	case DropNewest:
+		return "DropNewest"
+	default:
+		return "Unknown"
+	}
+}
+
+// SubscribeOptions configures a Subscription.
+type SubscribeOptions struct {
+	// Buffer is the capacity of the event channel.
+	Buffer int
+	// Overflow is the policy to apply when the buffer is full.
+	Overflow OverflowPolicy
+}
+
+// Subscription delivers graveyard events on a channel.
+type Subscription struct {
+	events  chan fsnotify.Event
+	dropped uint64
+
+	sendLock sync.Mutex
+	closed   bool
+}
+
+// Events returns the event channel.
+// The channel is closed when the subscription context is done.
+func (s *Subscription) Events() <-chan fsnotify.Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped due to overflow.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Subscribe watches a graveyard and sends events to the returned
+// Subscription. When the supplied context is canceled, watching will stop and
+// the event channel will be closed. Watch options (ex: WithShard) are passed
+// through to Watch.
+func Subscribe(ctx context.Context, graveyard string, opts SubscribeOptions, watchOpts ...Option) (*Subscription, error) {
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = DefaultSubscribeBuffer
+	}
+
+	s := &Subscription{
+		events: make(chan fsnotify.Event, buffer),
+	}
+
+	err := Watch(ctx, graveyard, func(event fsnotify.Event) {
+		s.send(ctx, event, opts.Overflow)
+	}, watchOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.sendLock.Lock()
+		defer s.sendLock.Unlock()
+		s.closed = true
+		close(s.events)
+	}()
+
+	return s, nil
+}
+
+func (s *Subscription) send(ctx context.Context, event fsnotify.Event, policy OverflowPolicy) {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	switch policy {
+	case DropNewest:
+		select {
+		case s.events <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.events <- event:
+				return
+			default:
+			}
+			// full: discard the oldest, unless the reader beat us to it
+			select {
+			case <-s.events:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.events <- event:
+		case <-ctx.Done():
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tombstone
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func testEvents(names ...string) []fsnotify.Event {
+	var events []fsnotify.Event
+	for _, name := range names {
+		events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Write})
+	}
+	return events
+}
+
+func drain(s *Subscription) []string {
+	var names []string
+	for {
+		select {
+		case event := <-s.events:
+			names = append(names, event.Name)
+		default:
+			return names
+		}
+	}
+}
+
+func TestSubscriptionOverflow(t *testing.T) {
+	tests := []struct {
+		policy      OverflowPolicy
+		wantNames   []string
+		wantDropped uint64
+	}{
+		{policy: DropNewest, wantNames: []string{"a", "b"}, wantDropped: 2},
+		{policy: DropOldest, wantNames: []string{"c", "d"}, wantDropped: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			s := &Subscription{events: make(chan fsnotify.Event, 2)}
+			for _, event := range testEvents("a", "b", "c", "d") {
+				s.send(context.Background(), event, tt.policy)
+			}
+
+			got := drain(s)
+			if len(got) != len(tt.wantNames) || got[0] != tt.wantNames[0] || got[1] != tt.wantNames[1] {
+				t.Errorf("expected events %v, got: %v", tt.wantNames, got)
+			}
+			if s.Dropped() != tt.wantDropped {
+				t.Errorf("expected %d dropped, got: %d", tt.wantDropped, s.Dropped())
+			}
+		})
+	}
+}
+
+func TestSubscriptionOverflowBlock(t *testing.T) {
+	s := &Subscription{events: make(chan fsnotify.Event, 1)}
+	s.send(context.Background(), testEvents("a")[0], Block)
+
+	sent := make(chan struct{})
+	go func() {
+		s.send(context.Background(), testEvents("b")[0], Block)
+		close(sent)
+	}()
+
+	select {
+	case <-sent:
+		t.Fatalf("expected send to block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if got := (<-s.events).Name; got != "a" {
+		t.Fatalf("expected event a, got: %s", got)
+	}
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatalf("expected send to unblock after reading")
+	}
+	if got := (<-s.events).Name; got != "b" {
+		t.Fatalf("expected event b, got: %s", got)
+	}
+	if s.Dropped() != 0 {
+		t.Fatalf("expected no drops, got: %d", s.Dropped())
+	}
+}
+
+func TestSubscriptionOverflowBlockCanceled(t *testing.T) {
+	s := &Subscription{events: make(chan fsnotify.Event, 1)}
+	s.send(context.Background(), testEvents("a")[0], Block)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := make(chan struct{})
+	go func() {
+		s.send(ctx, testEvents("b")[0], Block)
+		close(sent)
+	}()
+	cancel()
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatalf("expected blocked send to give up when canceled")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := Subscribe(ctx, graveyard, SubscribeOptions{})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if cap(s.events) != DefaultSubscribeBuffer {
+		t.Errorf("expected default buffer %d, got: %d", DefaultSubscribeBuffer, cap(s.events))
+	}
+
+	path := filepath.Join(graveyard, "app")
+	if err := ioutil.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	select {
+	case event := <-s.Events():
+		if event.Name != path {
+			t.Errorf("expected event for %s, got: %s", path, event.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for event")
+	}
+
+	cancel()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-s.Events():
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("expected events channel to be closed when canceled")
+		}
+	}
+}
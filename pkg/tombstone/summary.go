@@ -0,0 +1,101 @@
+package tombstone
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Phase is the lifecycle phase recorded by a tombstone.
+type Phase string
+
+const (
+	// PhasePending means not born yet.
+	PhasePending Phase = "Pending"
+	// PhaseRunning means born, but not dead or terminating.
+	PhaseRunning Phase = "Running"
+	// PhaseTerminating means asked to shut down, but not dead yet.
+	PhaseTerminating Phase = "Terminating"
+	// PhaseSucceeded means died with exit code 0, or one of the SuccessCodes.
+	PhaseSucceeded Phase = "Succeeded"
+	// PhaseFailed means died with any other (or unknown) exit code.
+	PhaseFailed Phase = "Failed"
+	// PhaseNeverBorn means the process failed to start.
+	PhaseNeverBorn Phase = "NeverBorn"
+)
+
+// Phase returns the current lifecycle phase.
+func (t *Tombstone) Phase() Phase {
+	switch {
+	case t.NeverBorn:
+		return PhaseNeverBorn
+	case t.Died != nil:
+		if t.Succeeded(t.SuccessCodes) {
+			return PhaseSucceeded
+		}
+		return PhaseFailed
+	case t.Terminating != nil:
+		return PhaseTerminating
+	case t.Born != nil:
+		return PhaseRunning
+	default:
+		return PhasePending
+	}
+}
+
+// Succeeded returns true if the tombstone recorded a death with exit code 0,
+// or one of the successCodes. An unknown exit code is not a success.
+func (t *Tombstone) Succeeded(successCodes []int) bool {
+	if t.Died == nil || t.ExitCode == nil {
+		return false
+	}
+	if *t.ExitCode == 0 {
+		return true
+	}
+	for _, code := range successCodes {
+		if *t.ExitCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary returns a one line, human readable summary of the tombstone.
+func (t *Tombstone) Summary() string {
+	var buffer strings.Builder
+	buffer.WriteString(t.Name)
+	buffer.WriteRune(' ')
+	buffer.WriteString(string(t.Phase()))
+
+	if t.ExitCode != nil {
+		fmt.Fprintf(&buffer, " exit=%d", *t.ExitCode)
+	}
+	if t.Born != nil {
+		fmt.Fprintf(&buffer, " born=%s", t.Born.Format(time.RFC3339))
+	}
+	if t.Died != nil {
+		fmt.Fprintf(&buffer, " died=%s", t.Died.Format(time.RFC3339))
+	}
+	if t.UID != nil {
+		fmt.Fprintf(&buffer, " uid=%d", *t.UID)
+	}
+	if t.GID != nil {
+		fmt.Fprintf(&buffer, " gid=%d", *t.GID)
+	}
+	if t.Status != "" {
+		fmt.Fprintf(&buffer, " status=%q", t.Status)
+	}
+	if t.Image != "" {
+		fmt.Fprintf(&buffer, " image=%s", t.Image)
+	}
+	if t.ImageDigest != "" {
+		fmt.Fprintf(&buffer, " digest=%s", t.ImageDigest)
+	}
+	if t.TraceID != "" {
+		fmt.Fprintf(&buffer, " trace=%s", t.TraceID)
+	}
+	if t.CorrelationID != "" {
+		fmt.Fprintf(&buffer, " correlation=%s", t.CorrelationID)
+	}
+	return buffer.String()
+}
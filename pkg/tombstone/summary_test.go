@@ -0,0 +1,146 @@
+package tombstone
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhase(t *testing.T) {
+	now := time.Now()
+	zero := 0
+	one := 1
+
+	tests := []struct {
+		name string
+		ts   *Tombstone
+		want Phase
+	}{
+		{name: "pending", ts: &Tombstone{}, want: PhasePending},
+		{name: "running", ts: &Tombstone{Born: &now}, want: PhaseRunning},
+		{name: "terminating", ts: &Tombstone{Born: &now, Terminating: &now}, want: PhaseTerminating},
+		{name: "succeeded", ts: &Tombstone{Born: &now, Died: &now, ExitCode: &zero}, want: PhaseSucceeded},
+		{name: "failed", ts: &Tombstone{Born: &now, Died: &now, ExitCode: &one}, want: PhaseFailed},
+		{name: "unknown exit code", ts: &Tombstone{Born: &now, Died: &now}, want: PhaseFailed},
+		{name: "never born", ts: &Tombstone{Died: &now, ExitCode: &one, NeverBorn: true}, want: PhaseNeverBorn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ts.Phase(); got != tt.want {
+				t.Fatalf("expected phase %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	born := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	died := born.Add(time.Minute)
+	exitCode := 0
+	ts := &Tombstone{
+		Name:        "app",
+		Born:        &born,
+		Died:        &died,
+		ExitCode:    &exitCode,
+		Image:       "repo/app:v1",
+		ImageDigest: "sha256:abc",
+	}
+
+	want := "app Succeeded exit=0 born=2020-01-02T03:04:05Z died=2020-01-02T03:05:05Z image=repo/app:v1 digest=sha256:abc"
+	if got := ts.Summary(); got != want {
+		t.Fatalf("expected summary:\n%s\ngot:\n%s", want, got)
+	}
+	if got := (&Tombstone{Name: "app"}).Summary(); got != "app Pending" {
+		t.Fatalf("expected pending summary, got: %s", got)
+	}
+}
+
+func TestImageRoundTrip(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", Image: "repo/app:v1", ImageDigest: "sha256:abc"}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	got := mustRead(t, graveyard, "app")
+	if got.Image != ts.Image || got.ImageDigest != ts.ImageDigest {
+		t.Fatalf("expected image %s@%s, got: %s@%s", ts.Image, ts.ImageDigest, got.Image, got.ImageDigest)
+	}
+}
+
+func TestSummaryTrace(t *testing.T) {
+	ts := &Tombstone{Name: "app", TraceID: "4bf92f3577b34da6", CorrelationID: "req-1"}
+
+	want := "app Pending trace=4bf92f3577b34da6 correlation=req-1"
+	if got := ts.Summary(); got != want {
+		t.Fatalf("expected summary:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", TraceID: "4bf92f3577b34da6", CorrelationID: "req-1"}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	got := mustRead(t, graveyard, "app")
+	if got.TraceID != ts.TraceID || got.CorrelationID != ts.CorrelationID {
+		t.Fatalf("expected trace %s and correlation %s, got: %s", ts.TraceID, ts.CorrelationID, got)
+	}
+
+	// omitted if not set
+	plain := mustRecordBirth(t, graveyard, "plain")
+	if got := mustRead(t, graveyard, "plain"); got.TraceID != "" || got.CorrelationID != "" {
+		t.Fatalf("expected no trace or correlation, got: %s", got)
+	}
+	if data := plain.String(); strings.Contains(data, "TraceID") || strings.Contains(data, "CorrelationID") {
+		t.Fatalf("expected empty ids to be omitted, got: %s", data)
+	}
+}
+
+func TestSucceeded(t *testing.T) {
+	now := time.Now()
+	code := func(c int) *int { return &c }
+
+	tests := []struct {
+		name         string
+		ts           *Tombstone
+		successCodes []int
+		want         bool
+	}{
+		{name: "alive", ts: &Tombstone{Born: &now}, want: false},
+		{name: "zero", ts: &Tombstone{Died: &now, ExitCode: code(0)}, want: true},
+		{name: "non-zero", ts: &Tombstone{Died: &now, ExitCode: code(2)}, want: false},
+		{name: "success code", ts: &Tombstone{Died: &now, ExitCode: code(2)}, successCodes: []int{1, 2}, want: true},
+		{name: "other code", ts: &Tombstone{Died: &now, ExitCode: code(3)}, successCodes: []int{1, 2}, want: false},
+		{name: "unknown exit code", ts: &Tombstone{Died: &now}, successCodes: []int{0}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ts.Succeeded(tt.successCodes); got != tt.want {
+				t.Fatalf("expected succeeded %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSuccessCodesPhase(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app", SuccessCodes: []int{2}}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordDeath(2); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	// recorded, so all readers agree on the phase
+	if got := mustRead(t, graveyard, "app"); got.Phase() != PhaseSucceeded {
+		t.Fatalf("expected succeeded with success code 2, got: %s", got.Phase())
+	}
+}
@@ -0,0 +1,34 @@
+package tombstone
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// NotifySystemd sends a state notification (ex: READY=1) to systemd, using
+// the sd_notify protocol, so kubexit can be supervised by a systemd unit
+// with Type=notify. If NOTIFY_SOCKET is not set (ex: not run by systemd),
+// nothing is sent.
+func NotifySystemd(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		// abstract namespace
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to systemd notify socket: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return fmt.Errorf("failed to notify systemd: %v", err)
+	}
+	return nil
+}
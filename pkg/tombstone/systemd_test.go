@@ -0,0 +1,109 @@
+package tombstone
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setenv sets the environment variable until the test ends.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("failed to set %s: %v", key, err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// fakeNotifySocket listens like systemd and sets NOTIFY_SOCKET to the
+// address, until the test ends.
+func fakeNotifySocket(t *testing.T, addr string) *net.UnixConn {
+	t.Helper()
+	name := addr
+	if name[0] == '@' {
+		name = "\x00" + name[1:]
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	setenv(t, "NOTIFY_SOCKET", addr)
+	return conn
+}
+
+// expectNotification reads the next notification, or fails the test.
+func expectNotification(t *testing.T, conn *net.UnixConn, want string) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("failed to read notification: %v", err)
+	}
+	if got := string(buf[:n]); got != want {
+		t.Fatalf("expected notification %q, got: %q", want, got)
+	}
+}
+
+func TestNotifySystemd(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+	}{
+		{name: "path", addr: filepath.Join(tempGraveyard(t), "notify")},
+		{name: "abstract", addr: fmt.Sprintf("@kubexit-test-%d", os.Getpid())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := fakeNotifySocket(t, tt.addr)
+			if err := NotifySystemd("READY=1"); err != nil {
+				t.Fatalf("failed to notify: %v", err)
+			}
+			expectNotification(t, conn, "READY=1")
+		})
+	}
+}
+
+func TestNotifySystemdUnset(t *testing.T) {
+	setenv(t, "NOTIFY_SOCKET", "")
+	// not run by systemd
+	if err := NotifySystemd("READY=1"); err != nil {
+		t.Fatalf("expected nothing to be sent, got: %v", err)
+	}
+}
+
+func TestNotifySystemdNoListener(t *testing.T) {
+	setenv(t, "NOTIFY_SOCKET", filepath.Join(tempGraveyard(t), "missing"))
+	if err := NotifySystemd("READY=1"); err == nil {
+		t.Fatalf("expected missing socket to fail")
+	}
+}
+
+func TestRecordReadyAndTerminatingNotifySystemd(t *testing.T) {
+	conn := fakeNotifySocket(t, filepath.Join(tempGraveyard(t), "notify"))
+	ts := mustRecordBirth(t, tempGraveyard(t), "app")
+
+	if err := ts.RecordReady(); err != nil {
+		t.Fatalf("failed to record ready: %v", err)
+	}
+	expectNotification(t, conn, "READY=1")
+	if got := mustRead(t, ts.Graveyard, "app"); got.Ready == nil {
+		t.Fatalf("expected ready to be recorded, got: %s", got)
+	}
+
+	if err := ts.RecordTerminating(); err != nil {
+		t.Fatalf("failed to record terminating: %v", err)
+	}
+	expectNotification(t, conn, "STOPPING=1")
+}
@@ -0,0 +1,144 @@
+package tombstone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimeFormat defines how tombstone timestamps are written.
+// Read accepts any format.
+type TimeFormat int
+
+const (
+	// TimeFormatRFC3339 writes timestamps as RFC3339 strings (with
+	// nanoseconds). This is the default.
+	TimeFormatRFC3339 TimeFormat = iota
+	// TimeFormatUnix writes timestamps as integer Unix epoch seconds.
+	TimeFormatUnix
+	// TimeFormatUnixNano writes timestamps as integer Unix epoch nanoseconds.
+	TimeFormatUnixNano
+)
+
+// unixNanoThreshold separates epoch seconds from epoch nanoseconds when
+// reading: seconds this large are tens of thousands of years away, and
+// nanoseconds this small are in the first hour of 1970.
+const unixNanoThreshold = 1e12
+
+// ParseTimeFormat parses a format name: rfc3339, unix, or unixnano.
+func ParseTimeFormat(name string) (TimeFormat, error) {
+	switch name {
+	case "rfc3339":
+		return TimeFormatRFC3339, nil
+	case "unix":
+		return TimeFormatUnix, nil
+	case "unixnano":
+		return TimeFormatUnixNano, nil
+	default:
+		return TimeFormatRFC3339, fmt.Errorf("unknown time format: %q", name)
+	}
+}
+
+// plainTombstone has the Tombstone fields, but not the methods, so it can
+// be marshaled without recursion.
+type plainTombstone Tombstone
+
+// epochTombstone overrides the Tombstone timestamps, to read and write
+// them in any TimeFormat.
+type epochTombstone struct {
+	*plainTombstone
+	Born          *jsonTime `json:",omitempty"`
+	Ready         *jsonTime `json:",omitempty"`
+	LastHeartbeat *jsonTime `json:",omitempty"`
+	Terminating   *jsonTime `json:",omitempty"`
+	Died          *jsonTime `json:",omitempty"`
+}
+
+// MarshalJSON writes the tombstone with timestamps in the TimeFormat.
+func (t *Tombstone) MarshalJSON() ([]byte, error) {
+	if t.TimeFormat == TimeFormatRFC3339 {
+		return json.Marshal((*plainTombstone)(t))
+	}
+	return json.Marshal(epochTombstone{
+		plainTombstone: (*plainTombstone)(t),
+		Born:           newJSONTime(t.Born, t.TimeFormat),
+		Ready:          newJSONTime(t.Ready, t.TimeFormat),
+		LastHeartbeat:  newJSONTime(t.LastHeartbeat, t.TimeFormat),
+		Terminating:    newJSONTime(t.Terminating, t.TimeFormat),
+		Died:           newJSONTime(t.Died, t.TimeFormat),
+	})
+}
+
+// UnmarshalJSON reads the tombstone with timestamps in any TimeFormat.
+// The TimeFormat is set to the format of the timestamps read, so that it
+// is preserved if re-written.
+func (t *Tombstone) UnmarshalJSON(data []byte) error {
+	aux := epochTombstone{
+		plainTombstone: (*plainTombstone)(t),
+	}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+	t.TimeFormat = TimeFormatRFC3339
+	t.Born = aux.Born.read(&t.TimeFormat)
+	t.Ready = aux.Ready.read(&t.TimeFormat)
+	t.LastHeartbeat = aux.LastHeartbeat.read(&t.TimeFormat)
+	t.Terminating = aux.Terminating.read(&t.TimeFormat)
+	t.Died = aux.Died.read(&t.TimeFormat)
+	return nil
+}
+
+// jsonTime is a timestamp in any TimeFormat.
+type jsonTime struct {
+	time   time.Time
+	format TimeFormat
+}
+
+func newJSONTime(t *time.Time, format TimeFormat) *jsonTime {
+	if t == nil {
+		return nil
+	}
+	return &jsonTime{time: *t, format: format}
+}
+
+// read returns the time and updates the format, if not nil.
+func (j *jsonTime) read(format *TimeFormat) *time.Time {
+	if j == nil {
+		return nil
+	}
+	*format = j.format
+	return &j.time
+}
+
+func (j jsonTime) MarshalJSON() ([]byte, error) {
+	switch j.format {
+	case TimeFormatUnix:
+		return strconv.AppendInt(nil, j.time.Unix(), 10), nil
+	case TimeFormatUnixNano:
+		return strconv.AppendInt(nil, j.time.UnixNano(), 10), nil
+	default:
+		return json.Marshal(j.time)
+	}
+}
+
+func (j *jsonTime) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		j.format = TimeFormatRFC3339
+		return json.Unmarshal(data, &j.time)
+	}
+	epoch, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: must be a string or integer: %s", data)
+	}
+	if epoch >= unixNanoThreshold || epoch <= -unixNanoThreshold {
+		j.format = TimeFormatUnixNano
+		j.time = time.Unix(0, epoch)
+	} else {
+		j.format = TimeFormatUnix
+		j.time = time.Unix(epoch, 0)
+	}
+	return nil
+}
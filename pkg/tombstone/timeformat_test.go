@@ -0,0 +1,92 @@
+package tombstone
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeFormat(t *testing.T) {
+	tests := map[string]TimeFormat{
+		"rfc3339":  TimeFormatRFC3339,
+		"unix":     TimeFormatUnix,
+		"unixnano": TimeFormatUnixNano,
+	}
+	for name, want := range tests {
+		got, err := ParseTimeFormat(name)
+		if err != nil || got != want {
+			t.Fatalf("expected format %v for %s, got %v: %v", want, name, got, err)
+		}
+	}
+	if _, err := ParseTimeFormat("iso"); err == nil {
+		t.Fatalf("expected unknown format to fail")
+	}
+}
+
+func TestTimeFormatRoundTrip(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+
+	tests := []struct {
+		name      string
+		format    TimeFormat
+		precision time.Duration
+		// the raw timestamp written
+		wantRaw string
+	}{
+		{name: "rfc3339", format: TimeFormatRFC3339, precision: time.Nanosecond, wantRaw: `"2020-01-02T03:04:05.123456789Z"`},
+		{name: "unix", format: TimeFormatUnix, precision: time.Second, wantRaw: "1577934245"},
+		{name: "unixnano", format: TimeFormatUnixNano, precision: time.Nanosecond, wantRaw: "1577934245123456789"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graveyard := tempGraveyard(t)
+			ts := &Tombstone{
+				Graveyard:     graveyard,
+				Name:          "app",
+				TimeFormat:    tt.format,
+				Born:          &at,
+				Ready:         &at,
+				LastHeartbeat: &at,
+				Terminating:   &at,
+				Died:          &at,
+			}
+			if err := ts.Write(); err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+
+			data, err := ioutil.ReadFile(ts.Path())
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if !strings.Contains(string(data), "Ready: "+tt.wantRaw) {
+				t.Fatalf("expected ready written as %s, got:\n%s", tt.wantRaw, data)
+			}
+
+			got := mustRead(t, graveyard, "app")
+			want := at.Truncate(tt.precision)
+			for field, value := range map[string]*time.Time{
+				"Born":          got.Born,
+				"Ready":         got.Ready,
+				"LastHeartbeat": got.LastHeartbeat,
+				"Terminating":   got.Terminating,
+				"Died":          got.Died,
+			} {
+				if value == nil || !value.Equal(want) {
+					t.Fatalf("expected %s to be %s, got: %v", field, want, value)
+				}
+			}
+			// preserved, if re-written
+			if got.TimeFormat != tt.format {
+				t.Fatalf("expected format %v to be read, got: %v", tt.format, got.TimeFormat)
+			}
+		})
+	}
+}
+
+func TestTimeFormatInvalid(t *testing.T) {
+	ts := &Tombstone{}
+	if err := ts.UnmarshalJSON([]byte(`{"Born": true}`)); err == nil {
+		t.Fatalf("expected invalid timestamp to fail")
+	}
+}
@@ -0,0 +1,55 @@
+package tombstone
+
+import (
+	"sort"
+	"time"
+)
+
+// TimelineEntry is a single lifecycle event of a container.
+type TimelineEntry struct {
+	Name  string
+	Event EventType
+	Time  time.Time
+}
+
+// Timeline reads all the tombstones in a graveyard and returns their
+// lifecycle events (birth, ready, terminating, death), in chronological
+// order across all containers. Events that were not recorded are omitted.
+// Events at the same time are ordered by name and then lifecycle order.
+func Timeline(graveyard string, opts ...Option) ([]TimelineEntry, error) {
+	tombstones, err := ReadAll(graveyard, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TimelineEntry
+	for _, ts := range tombstones {
+		entries = append(entries, ts.timeline()...)
+	}
+
+	// ReadAll sorts by name and timeline() is in lifecycle order, so a stable
+	// sort keeps ties in that order.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// timeline returns the recorded lifecycle events, in lifecycle order.
+func (t *Tombstone) timeline() []TimelineEntry {
+	var entries []TimelineEntry
+	add := func(event EventType, at *time.Time) {
+		if at != nil {
+			entries = append(entries, TimelineEntry{
+				Name:  t.Name,
+				Event: event,
+				Time:  *at,
+			})
+		}
+	}
+	add(EventBirth, t.Born)
+	add(EventReady, t.Ready)
+	add(EventTerminating, t.Terminating)
+	add(EventDeath, t.Died)
+	return entries
+}
@@ -0,0 +1,65 @@
+package tombstone
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		t := start.Add(time.Duration(seconds) * time.Second)
+		return &t
+	}
+
+	tombstones := []*Tombstone{
+		{Name: "app", Born: at(2), Ready: at(3), Terminating: at(5), Died: at(6)},
+		// ties with app birth, ordered by name
+		{Name: "b", Born: at(2), Died: at(4)},
+		{Name: "db", Born: at(0), Ready: at(1)},
+		{Name: "pending"},
+	}
+	for _, ts := range tombstones {
+		ts.Graveyard = graveyard
+		if err := ts.Write(); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	entries, err := Timeline(graveyard)
+	if err != nil {
+		t.Fatalf("failed to read timeline: %v", err)
+	}
+
+	want := []TimelineEntry{
+		{Name: "db", Event: EventBirth, Time: *at(0)},
+		{Name: "db", Event: EventReady, Time: *at(1)},
+		{Name: "app", Event: EventBirth, Time: *at(2)},
+		{Name: "b", Event: EventBirth, Time: *at(2)},
+		{Name: "app", Event: EventReady, Time: *at(3)},
+		{Name: "b", Event: EventDeath, Time: *at(4)},
+		{Name: "app", Event: EventTerminating, Time: *at(5)},
+		{Name: "app", Event: EventDeath, Time: *at(6)},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got: %v", len(want), entries)
+	}
+	for i := range want {
+		got := entries[i]
+		if got.Name != want[i].Name || got.Event != want[i].Event || !got.Time.Equal(want[i].Time) {
+			t.Fatalf("expected entry %d to be %v, got: %v", i, want[i], got)
+		}
+	}
+}
+
+func TestTimelineEmpty(t *testing.T) {
+	entries, err := Timeline(tempGraveyard(t))
+	if err != nil {
+		t.Fatalf("failed to read timeline: %v", err)
+	}
+	if !reflect.DeepEqual(entries, []TimelineEntry(nil)) {
+		t.Fatalf("expected no entries, got: %v", entries)
+	}
+}
@@ -1,88 +1,523 @@
 package tombstone
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"sigs.k8s.io/yaml"
 )
 
 type Tombstone struct {
-	Born     *time.Time `json:",omitempty"`
-	Died     *time.Time `json:",omitempty"`
-	ExitCode *int       `json:",omitempty"`
+	Born *time.Time `json:",omitempty"`
+	// BirthWaitDuration is how long the birth was blocked waiting for birth
+	// deps, in nanoseconds. The supervisor records the time spent waiting
+	// for the birth deps to be ready, according to Kubernetes.
+	BirthWaitDuration *time.Duration `json:",omitempty"`
+	// Ready is when the process was ready (ex: to serve), after birth.
+	Ready *time.Time `json:",omitempty"`
+	// LastHeartbeat is when the supervisor last confirmed it was alive.
+	LastHeartbeat *time.Time `json:",omitempty"`
+	// Terminating is when the process was asked to shut down, before it died.
+	Terminating *time.Time `json:",omitempty"`
+	Died        *time.Time `json:",omitempty"`
+	ExitCode    *int       `json:",omitempty"`
+	// SuccessCodes are the non-zero exit codes that also mean success
+	// (ex: 2 for "no work to do"), so all readers agree on the phase.
+	SuccessCodes []int `json:",omitempty"`
+	// CascadePolicy is whether the death should shut down dependents.
+	// Default: always.
+	CascadePolicy CascadePolicy `json:",omitempty"`
+	// WrapperExitCode is the exit code of the supervisor, if it failed for
+	// reasons unrelated to the process (ex: a birth dep timed out), so that
+	// a broken supervisor can be told apart from a crashed process.
+	WrapperExitCode *int `json:",omitempty"`
+	// NeverBorn is true if the process failed to start (ex: exec failed).
+	NeverBorn bool `json:",omitempty"`
+	// Status describes a death not recorded by the supervisor
+	// (ex: PresumedDeadStatus).
+	Status string `json:",omitempty"`
+	// Error is the chain of errors that caused the death, outermost first.
+	Error []string `json:",omitempty"`
+
+	// PeakMemoryBytes is the peak memory usage of the container cgroup.
+	PeakMemoryBytes *uint64 `json:",omitempty"`
+	// CPUSeconds is the total CPU time used by the container cgroup.
+	CPUSeconds *float64 `json:",omitempty"`
+
+	// PodGeneration identifies the pod generation that wrote the tombstone
+	// (ex: pod-template-hash or pod UID), to distinguish stale tombstones
+	// from previous generations with the same name.
+	PodGeneration string `json:",omitempty"`
+	// OwnerRef identifies the owner of the pod that wrote the tombstone
+	// (ex: ReplicaSet name).
+	OwnerRef string `json:",omitempty"`
+	// Image is the container image that ran (ex: repo/name:tag).
+	Image string `json:",omitempty"`
+	// ImageDigest is the digest of the container image that ran.
+	ImageDigest string `json:",omitempty"`
+
+	// BirthDeps and DeathDeps are the declared dependencies of the process,
+	// so the coordination graph can be discovered from the graveyard.
+	BirthDeps []string `json:",omitempty"`
+	DeathDeps []string `json:",omitempty"`
+
+	// RestartReason is why the previous instance of the container
+	// terminated, according to Kubernetes (ex: OOMKilled), if restarted.
+	RestartReason string `json:",omitempty"`
+
+	// UID and GID are the effective user and group IDs the process ran as.
+	UID *int `json:",omitempty"`
+	GID *int `json:",omitempty"`
+
+	// NodeInfo describes the node that ran the process.
+	NodeInfo *NodeInfo `json:",omitempty"`
+
+	// PodInstanceID identifies the pod instance that wrote the tombstone
+	// (ex: pod UID), to distinguish pods recreated with the same name.
+	PodInstanceID string `json:",omitempty"`
+
+	// TraceID and CorrelationID identify the distributed trace and the
+	// request or job that spawned the container, so the tombstone can be
+	// tied back to them.
+	TraceID       string `json:",omitempty"`
+	CorrelationID string `json:",omitempty"`
+
+	// ExitCodeHistory is the exit codes of the latest deaths of the
+	// container, across restarts, oldest first.
+	ExitCodeHistory []int `json:",omitempty"`
 
 	Graveyard string `json:"-"`
 	Name      string `json:"-"`
 
+	// Key, if set, encrypts the tombstone contents when written.
+	Key *Key `json:"-"`
+	// Shard, if set, places the tombstone in a graveyard subdirectory.
+	Shard ShardFunc `json:"-"`
+	// OnNoSpace is what to do if the graveyard is full when writing.
+	OnNoSpace NoSpacePolicy `json:"-"`
+	// Mirror, if set, receives a JSON line for each successful write.
+	Mirror io.Writer `json:"-"`
+	// ExitCodeTransform, if set, normalizes exit codes before they are
+	// recorded (ex: to strip an offset added by a runtime shim).
+	ExitCodeTransform func(raw int) int `json:"-"`
+	// History, if set, appends each write to the tombstone file, retaining
+	// recent history (see ReadHistory), instead of replacing it.
+	History *HistoryLog `json:"-"`
+	// TimeFormat is how timestamps are written. Default: RFC3339.
+	TimeFormat TimeFormat `json:"-"`
+	// MinHeartbeatInterval, if set, throttles Heartbeat writes to at most
+	// one per interval.
+	MinHeartbeatInterval time.Duration `json:"-"`
+	// HeartbeatSidecar, if true, writes heartbeats to a separate file
+	// (<name>.heartbeat), instead of re-writing the tombstone, so the
+	// tombstone only changes on lifecycle transitions. Read merges it.
+	HeartbeatSidecar bool `json:"-"`
+	// MaxExitCodeHistory, if positive, records the exit code of each death
+	// in the ExitCodeHistory, carried forward from the prior tombstone at
+	// birth, keeping only the latest MaxExitCodeHistory codes.
+	MaxExitCodeHistory int `json:"-"`
+
 	fileLock sync.Mutex
+	// heartbeatWritten is when the last heartbeat write happened.
+	heartbeatWritten time.Time
+	// heartbeatPending is true if a throttled heartbeat hasn't been written.
+	heartbeatPending bool
+	// exitCodesLoaded is true if the prior ExitCodeHistory was loaded.
+	exitCodesLoaded bool
 }
 
 func (t *Tombstone) Path() string {
+	if t.Shard != nil {
+		return filepath.Join(t.Graveyard, t.Shard(t.Name), t.Name)
+	}
 	return filepath.Join(t.Graveyard, t.Name)
 }
 
-// Write a tombstone file, truncating before writing.
+// Write a tombstone file, atomically replacing any existing file.
 // If the FilePath directories do not exist, they will be created.
 func (t *Tombstone) Write() error {
+	return t.writeEvent(EventWrite, nil)
+}
+
+// writeEvent applies the update, if any, and writes the tombstone file,
+// under the file lock, so that concurrent writers (ex: heartbeats) never see
+// a partial update. Then the event is mirrored, if a Mirror is set.
+// If the update returns false, nothing is written.
+func (t *Tombstone) writeEvent(event EventType, update func() bool) error {
 	// one write at a time
 	t.fileLock.Lock()
 	defer t.fileLock.Unlock()
 
-	err := os.MkdirAll(t.Graveyard, os.ModePerm)
+	if update != nil && !update() {
+		return nil
+	}
+	err := t.write()
 	if err != nil {
 		return err
 	}
+	t.mirror(event)
+	return nil
+}
 
-	// does not exit
-	file, err := os.Create(t.Path())
+// write the tombstone file. The caller must hold the file lock.
+func (t *Tombstone) write() error {
+	err := os.MkdirAll(filepath.Dir(t.Path()), os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("failed to create tombstone file: %v", err)
+		return err
+	}
+
+	if t.History != nil {
+		err = t.appendRecord()
+	} else {
+		var pretty []byte
+		pretty, err = t.marshal()
+		if err != nil {
+			return err
+		}
+		err = writeFileAtomic(t.Path(), pretty)
+	}
+	if err != nil && isNoSpace(err) {
+		err = t.handleNoSpace(err)
+	}
+	if err == nil {
+		// includes the latest heartbeat
+		t.heartbeatPending = false
 	}
-	defer file.Close()
+	return err
+}
 
+// marshal the tombstone as yaml, encrypted if a Key is set.
+func (t *Tombstone) marshal() ([]byte, error) {
 	pretty, err := yaml.Marshal(t)
 	if err != nil {
-		return fmt.Errorf("failed to marshal tombstone yaml: %v", err)
+		return nil, fmt.Errorf("failed to marshal tombstone yaml: %v", err)
+	}
+
+	if t.Key != nil {
+		pretty, err = encrypt(t.Key, pretty)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt tombstone: %v", err)
+		}
+	}
+	return pretty, nil
+}
+
+// writeFileAtomic writes the data to a hidden temp file in the same
+// directory and renames it over the path, so readers never see a partial
+// file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	// TempFile uses 0600, but readers may run as other users
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to chmod temp file: %w", err)
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
-	file.Write(pretty)
 	return nil
 }
 
 func (t *Tombstone) RecordBirth() error {
-	born := time.Now()
-	t.Born = &born
-
 	log.Printf("Creating tombstone: %s\n", t.Path())
-	err := t.Write()
+	err := t.writeEvent(EventBirth, func() bool {
+		born := time.Now()
+		t.Born = &born
+
+		if t.MaxExitCodeHistory > 0 && !t.exitCodesLoaded {
+			t.loadExitCodeHistory()
+		}
+		return true
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create tombstone: %v", err)
 	}
 	return nil
 }
 
+// RecordReady records that the process is ready and notifies systemd
+// (READY=1), if run by systemd.
+func (t *Tombstone) RecordReady() error {
+	log.Printf("Updating tombstone: %s\n", t.Path())
+	err := t.writeEvent(EventReady, func() bool {
+		ready := time.Now()
+		t.Ready = &ready
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tombstone: %v", err)
+	}
+
+	err = NotifySystemd("READY=1")
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+	}
+	return nil
+}
+
+// Heartbeat records that the supervisor is still alive, so that observers
+// can tell a long-lived process from a crashed supervisor.
+// If HeartbeatSidecar is true, only the heartbeat file is written.
+// If MinHeartbeatInterval is set, heartbeats after a write are only recorded
+// in memory until the interval elapses. Other writes include them, or use
+// FlushHeartbeat.
+func (t *Tombstone) Heartbeat() error {
+	t.fileLock.Lock()
+	defer t.fileLock.Unlock()
+
+	now := time.Now()
+	t.LastHeartbeat = &now
+
+	if t.MinHeartbeatInterval > 0 && now.Sub(t.heartbeatWritten) < t.MinHeartbeatInterval {
+		// throttled
+		t.heartbeatPending = true
+		return nil
+	}
+	return t.writeHeartbeat()
+}
+
+// FlushHeartbeat writes the last heartbeat, if throttled by
+// MinHeartbeatInterval and not written since.
+func (t *Tombstone) FlushHeartbeat() error {
+	t.fileLock.Lock()
+	defer t.fileLock.Unlock()
+
+	if !t.heartbeatPending {
+		return nil
+	}
+	return t.writeHeartbeat()
+}
+
+// writeHeartbeat writes the heartbeat to the tombstone or sidecar file.
+// The caller must hold the file lock.
+func (t *Tombstone) writeHeartbeat() error {
+	var err error
+	if t.HeartbeatSidecar {
+		err = t.writeHeartbeatSidecar()
+	} else {
+		err = t.write()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update tombstone heartbeat: %v", err)
+	}
+	t.heartbeatWritten = time.Now()
+	t.heartbeatPending = false
+	t.mirror(EventHeartbeat)
+	return nil
+}
+
+// RecordTerminating records that the process is about to die, so that
+// dependents can start shutting down early, and notifies systemd
+// (STOPPING=1), if run by systemd. Skipped if not yet born or already dead.
+// The check and the write happen under the file lock, so that a concurrent
+// death is never overwritten.
+func (t *Tombstone) RecordTerminating() error {
+	skipped := false
+	err := t.writeEvent(EventTerminating, func() bool {
+		if t.Born == nil || t.Died != nil {
+			skipped = true
+			return false
+		}
+		terminating := time.Now()
+		t.Terminating = &terminating
+
+		log.Printf("Updating tombstone: %s\n", t.Path())
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tombstone: %v", err)
+	}
+	if skipped {
+		return nil
+	}
+
+	err = NotifySystemd("STOPPING=1")
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+	}
+	return nil
+}
+
 func (t *Tombstone) RecordDeath(exitCode int) error {
-	code := exitCode
+	return t.recordDeath(&exitCode, nil)
+}
+
+// recordDeath applies the update, if any, and records a death, under the
+// file lock. A nil exit code records no ExitCode.
+func (t *Tombstone) recordDeath(exitCode *int, update func()) error {
+	log.Printf("Updating tombstone: %s\n", t.Path())
+	err := t.writeEvent(EventDeath, func() bool {
+		if update != nil {
+			update()
+		}
+		died := time.Now()
+		t.Died = &died
+		if exitCode != nil {
+			code := t.transformExitCode(*exitCode)
+			t.ExitCode = &code
+			t.appendExitCode(code)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tombstone: %v", err)
+	}
+	return nil
+}
+
+// RecordDeathOnce records a death, unless one is already recorded, either in
+// memory or on disk, so that duplicate calls (ex: from a signal handler and
+// deferred cleanup) preserve the first death.
+func (t *Tombstone) RecordDeathOnce(exitCode int) error {
+	// hold the lock between checking and writing
+	t.fileLock.Lock()
+	defer t.fileLock.Unlock()
+
+	if t.Died != nil {
+		log.Printf("Skipping death: already recorded: %s\n", t.Path())
+		return nil
+	}
+
+	existing, err := Read(t.Graveyard, t.Name, WithShard(t.Shard), WithKeys(t.keys()...))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read tombstone: %v", err)
+	}
+	if existing != nil && existing.Died != nil {
+		log.Printf("Skipping death: already recorded on disk: %s\n", t.Path())
+		t.Died = existing.Died
+		t.ExitCode = existing.ExitCode
+		return nil
+	}
+
+	code := t.transformExitCode(exitCode)
 	died := time.Now()
 	t.Died = &died
 	t.ExitCode = &code
+	t.appendExitCode(code)
 
 	log.Printf("Updating tombstone: %s\n", t.Path())
-	err := t.Write()
+	err = t.write()
 	if err != nil {
 		return fmt.Errorf("failed to update tombstone: %v", err)
 	}
+	t.mirror(EventDeath)
 	return nil
 }
 
+// transformExitCode applies the ExitCodeTransform, if set.
+func (t *Tombstone) transformExitCode(raw int) int {
+	if t.ExitCodeTransform == nil {
+		return raw
+	}
+	return t.ExitCodeTransform(raw)
+}
+
+// RecordNeverBorn records that the process failed to start, along with the
+// cause. Died is also recorded, so that death dependents are not left waiting.
+func (t *Tombstone) RecordNeverBorn(exitCode int, cause error) error {
+	return t.recordDeath(&exitCode, func() {
+		t.NeverBorn = true
+		t.Error = errorChain(cause, MaxErrorChainLength)
+	})
+}
+
+// RecordExit records a death from the wait status of the process. If it was
+// killed by a signal, the exit code is -1 (like os.ProcessState.ExitCode)
+// and the signal is recorded as the Error.
+func (t *Tombstone) RecordExit(status syscall.WaitStatus) error {
+	if status.Signaled() {
+		return t.RecordDeathWithError(-1, fmt.Errorf("signal: %v", status.Signal()))
+	}
+	code := status.ExitStatus()
+	if code != 0 {
+		return t.RecordDeathWithError(code, fmt.Errorf("exit status %d", code))
+	}
+	return t.RecordDeathWithError(code, nil)
+}
+
+// RecordWrapperFailure records a death caused by the supervisor failing,
+// along with the supervisor exit code, the process exit code, and the cause.
+func (t *Tombstone) RecordWrapperFailure(wrapperExitCode, exitCode int, cause error) error {
+	return t.recordDeath(&exitCode, func() {
+		code := wrapperExitCode
+		t.WrapperExitCode = &code
+		t.Error = errorChain(cause, MaxErrorChainLength)
+	})
+}
+
+// RecordWrapperFailureBeforeStart records a death caused by the supervisor
+// failing before the process was started (ex: a birth dep timed out), along
+// with the supervisor exit code and the cause. No ExitCode is recorded,
+// because the process never ran.
+func (t *Tombstone) RecordWrapperFailureBeforeStart(wrapperExitCode int, cause error) error {
+	return t.recordDeath(nil, func() {
+		code := wrapperExitCode
+		t.WrapperExitCode = &code
+		t.Error = errorChain(cause, MaxErrorChainLength)
+	})
+}
+
+// MaxErrorChainLength is the maximum number of bytes of error messages
+// recorded by RecordDeathWithError.
+const MaxErrorChainLength = 4096
+
+// RecordDeathWithError records a death, along with the chain of errors that
+// caused it. A nil error records no chain.
+func (t *Tombstone) RecordDeathWithError(exitCode int, cause error) error {
+	return t.recordDeath(&exitCode, func() {
+		t.Error = errorChain(cause, MaxErrorChainLength)
+	})
+}
+
+// errorChain unwraps the error and returns the message of each layer.
+// Each layer's message is trimmed of the wrapped error's message, so that
+// the chain isn't repetitive. The total length is capped at max bytes.
+func errorChain(err error, max int) []string {
+	var chain []string
+	length := 0
+	for err != nil {
+		msg := err.Error()
+		next := errors.Unwrap(err)
+		if next != nil {
+			msg = strings.TrimSuffix(msg, ": "+next.Error())
+		}
+		if length+len(msg) > max {
+			msg = msg[:max-length] + "..."
+			chain = append(chain, msg)
+			break
+		}
+		length += len(msg)
+		chain = append(chain, msg)
+		err = next
+	}
+	return chain
+}
+
 func (t *Tombstone) String() string {
 	inline, err := json.Marshal(t)
 	if err != nil {
@@ -93,15 +528,47 @@ func (t *Tombstone) String() string {
 }
 
 // Read a tombstone from a graveyard.
-func Read(graveyard, name string) (*Tombstone, error) {
+func Read(graveyard, name string, opts ...Option) (*Tombstone, error) {
+	config := newOptions(opts)
+
 	t := Tombstone{
 		Graveyard: graveyard,
 		Name:      name,
+		Shard:     config.shard,
 	}
 
 	bytes, err := ioutil.ReadFile(t.Path())
 	if err != nil {
-		return nil, fmt.Errorf("failed to read tombstone file: %v", err)
+		// wrap, so callers can check for os.ErrNotExist
+		return nil, fmt.Errorf("failed to read tombstone file: %w", err)
+	}
+
+	if isEncrypted(bytes) {
+		keyID, err := encryptedKeyID(bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := config.keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("failed to decrypt tombstone: unknown key id: %q", keyID)
+		}
+		bytes, err = decrypt(key, bytes)
+		if err != nil {
+			return nil, err
+		}
+		// keep encrypting, if re-written
+		t.Key = key
+	}
+
+	if isHistoryLog(bytes) {
+		records := historyRecords(bytes)
+		if len(records) == 0 {
+			return nil, errors.New("failed to read tombstone history: no complete records")
+		}
+		// json is yaml
+		bytes = records[len(records)-1]
+		// keep appending, if re-written
+		t.History = &HistoryLog{}
 	}
 
 	err = yaml.Unmarshal(bytes, &t)
@@ -109,63 +576,33 @@ func Read(graveyard, name string) (*Tombstone, error) {
 		return nil, fmt.Errorf("failed to unmarshal tombstone yaml: %v", err)
 	}
 
-	return &t, nil
-}
-
-type EventHandler func(fsnotify.Event)
-
-// LoggingEventHandler is an example EventHandler that logs fsnotify events
-func LoggingEventHandler(event fsnotify.Event) {
-	if event.Op&fsnotify.Create == fsnotify.Create {
-		log.Printf("Tombstone Watch: file created: %s\n", event.Name)
-	}
-	if event.Op&fsnotify.Remove == fsnotify.Remove {
-		log.Printf("Tombstone Watch: file removed: %s\n", event.Name)
-	}
-	if event.Op&fsnotify.Write == fsnotify.Write {
-		log.Printf("Tombstone Watch: file modified: %s\n", event.Name)
-	}
-	if event.Op&fsnotify.Rename == fsnotify.Rename {
-		log.Printf("Tombstone Watch: file renamed: %s\n", event.Name)
+	err = t.readHeartbeatSidecar()
+	if err != nil {
+		return nil, err
 	}
-	if event.Op&fsnotify.Chmod == fsnotify.Chmod {
-		log.Printf("Tombstone Watch: file chmoded: %s\n", event.Name)
+
+	if config.migrate != nil {
+		return migrate(&t, config)
 	}
+	return &t, nil
 }
 
-// Watch a graveyard and call the eventHandler (asyncronously) when an
-// event happens. When the supplied context is canceled, watching will stop.
-func Watch(ctx context.Context, graveyard string, eventHandler EventHandler) error {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return fmt.Errorf("failed to create watcher: %v", err)
+// ReadOrDefault reads a tombstone from a graveyard, or returns a pending
+// (not born) tombstone with the Graveyard and Name set, if it can't be read.
+// Returns true if the tombstone was read. Read errors other than a missing
+// file are logged.
+func ReadOrDefault(graveyard, name string, opts ...Option) (*Tombstone, bool) {
+	t, err := Read(graveyard, name, opts...)
+	if err == nil {
+		return t, true
 	}
-
-	go func() {
-		defer watcher.Close()
-		for {
-			select {
-			case <-ctx.Done():
-				log.Printf("Tombstone Watch(%s): done\n", graveyard)
-				return
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return
-				}
-				eventHandler(event)
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					return
-				}
-				log.Printf("Tombstone Watch(%s): error: %v\n", graveyard, err)
-				// TODO: wrap ctx with WithCancel and cancel on terminal errors, if any
-			}
-		}
-	}()
-
-	err = watcher.Add(graveyard)
-	if err != nil {
-		return fmt.Errorf("failed to add watcher: %v", err)
+	if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error: %v\n", err)
 	}
-	return nil
+	config := newOptions(opts)
+	return &Tombstone{
+		Graveyard: graveyard,
+		Name:      name,
+		Shard:     config.shard,
+	}, false
 }
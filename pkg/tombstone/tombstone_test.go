@@ -0,0 +1,335 @@
+package tombstone
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// tempGraveyard returns a new, empty graveyard dir, removed after the test.
+func tempGraveyard(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "graveyard")
+	if err != nil {
+		t.Fatalf("failed to create graveyard: %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+// mustRead reads the named tombstone or fails the test.
+func mustRead(t *testing.T, graveyard, name string, opts ...Option) *Tombstone {
+	t.Helper()
+	ts, err := Read(graveyard, name, opts...)
+	if err != nil {
+		t.Fatalf("failed to read tombstone %s: %v", name, err)
+	}
+	return ts
+}
+
+func TestPodGenerationRoundTrip(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{
+		Graveyard:     graveyard,
+		Name:          "app",
+		PodGeneration: "abc123",
+		OwnerRef:      "my-replicaset",
+	}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.PodGeneration != "abc123" {
+		t.Errorf("expected pod generation abc123, got: %q", got.PodGeneration)
+	}
+	if got.OwnerRef != "my-replicaset" {
+		t.Errorf("expected owner ref my-replicaset, got: %q", got.OwnerRef)
+	}
+}
+
+func TestErrorChain(t *testing.T) {
+	root := errors.New("permission denied")
+	wrapped := fmt.Errorf("failed to open config: %w", root)
+	top := fmt.Errorf("failed to start: %w", wrapped)
+
+	got := errorChain(top, MaxErrorChainLength)
+	want := []string{"failed to start", "failed to open config", "permission denied"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected chain %q, got: %q", want, got)
+	}
+
+	if chain := errorChain(nil, MaxErrorChainLength); chain != nil {
+		t.Fatalf("expected no chain for nil error, got: %q", chain)
+	}
+}
+
+func TestErrorChainMaxLength(t *testing.T) {
+	err := fmt.Errorf("0123456789: %w", errors.New("abcdefghij"))
+
+	got := errorChain(err, 15)
+	want := []string{"0123456789", "abcde..."}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected chain %q, got: %q", want, got)
+	}
+}
+
+func TestRecordDeathWithError(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	cause := fmt.Errorf("exit status 2: %w", errors.New("out of cheese"))
+	if err := ts.RecordDeathWithError(2, cause); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.ExitCode == nil || *got.ExitCode != 2 {
+		t.Errorf("expected exit code 2, got: %v", got.ExitCode)
+	}
+	if want := []string{"exit status 2", "out of cheese"}; !reflect.DeepEqual(got.Error, want) {
+		t.Errorf("expected error chain %q, got: %q", want, got.Error)
+	}
+
+	if err := ts.RecordDeathWithError(0, nil); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	got = mustRead(t, graveyard, "app")
+	if got.Error != nil {
+		t.Errorf("expected no error chain for a nil error, got: %q", got.Error)
+	}
+}
+
+func TestRecordTerminating(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	if err := ts.RecordTerminating(); err != nil {
+		t.Fatalf("failed to record terminating: %v", err)
+	}
+	if ts.Terminating != nil {
+		t.Fatalf("expected terminating to be skipped before birth")
+	}
+
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordTerminating(); err != nil {
+		t.Fatalf("failed to record terminating: %v", err)
+	}
+	got := mustRead(t, graveyard, "app")
+	if got.Terminating == nil || got.Died != nil {
+		t.Fatalf("expected terminating, but not dead, got: %s", got)
+	}
+}
+
+func TestRecordTerminatingAfterDeath(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	if err := ts.RecordTerminating(); err != nil {
+		t.Fatalf("failed to record terminating: %v", err)
+	}
+	if ts.Terminating != nil {
+		t.Fatalf("expected terminating to be skipped after death")
+	}
+}
+
+func TestRecordTerminatingConcurrentDeath(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		graveyard := tempGraveyard(t)
+
+		ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+		if err := ts.RecordBirth(); err != nil {
+			t.Fatalf("failed to record birth: %v", err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := ts.RecordTerminating(); err != nil {
+				t.Errorf("failed to record terminating: %v", err)
+			}
+		}()
+		if err := ts.RecordDeath(0); err != nil {
+			t.Fatalf("failed to record death: %v", err)
+		}
+		<-done
+
+		// the death is never overwritten by the terminating write
+		got := mustRead(t, graveyard, "app")
+		if got.Died == nil {
+			t.Fatalf("expected death to be preserved, got: %s", got)
+		}
+	}
+}
+
+func TestRecordNeverBorn(t *testing.T) {
+	graveyard := tempGraveyard(t)
+
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	cause := fmt.Errorf("failed to start child process: %w", os.ErrNotExist)
+	if err := ts.RecordNeverBorn(127, cause); err != nil {
+		t.Fatalf("failed to record never born: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if !got.NeverBorn || got.Born != nil {
+		t.Fatalf("expected never born, got: %s", got)
+	}
+	// so death dependents are not left waiting
+	if got.Died == nil || got.ExitCode == nil || *got.ExitCode != 127 {
+		t.Fatalf("expected death with exit code 127, got: %s", got)
+	}
+	want := []string{"failed to start child process", "file does not exist"}
+	if !reflect.DeepEqual(got.Error, want) {
+		t.Fatalf("expected error chain %q, got: %q", want, got.Error)
+	}
+}
+
+func TestReadOrDefault(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "born")
+	if err := ioutil.WriteFile(filepath.Join(graveyard, "corrupt"), []byte("Born: [\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		wantOK   bool
+		wantBorn bool
+	}{
+		{name: "born", wantOK: true, wantBorn: true},
+		{name: "missing", wantOK: false},
+		{name: "corrupt", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, ok := ReadOrDefault(graveyard, tt.name)
+			if ok != tt.wantOK {
+				t.Fatalf("expected read %v, got: %v", tt.wantOK, ok)
+			}
+			if (ts.Born != nil) != tt.wantBorn {
+				t.Fatalf("expected born %v, got: %s", tt.wantBorn, ts)
+			}
+			if ts.Graveyard != graveyard || ts.Name != tt.name {
+				t.Fatalf("expected graveyard and name to be set, got: %s/%s", ts.Graveyard, ts.Name)
+			}
+			if !ok && ts.Phase() != PhasePending {
+				t.Fatalf("expected default to be pending, got: %s", ts.Phase())
+			}
+		})
+	}
+}
+
+func TestReadOrDefaultSharded(t *testing.T) {
+	ts, ok := ReadOrDefault(tempGraveyard(t), "missing", WithShard(HashShard))
+	if ok {
+		t.Fatalf("expected missing tombstone")
+	}
+	// so the default can be written to the right place
+	if ts.Shard == nil {
+		t.Fatalf("expected shard to be set on the default")
+	}
+}
+
+func TestExitCodeTransform(t *testing.T) {
+	// strips an offset added by a runtime shim
+	stripOffset := func(raw int) int {
+		if raw >= 128 {
+			return raw - 128
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name   string
+		record func(*Tombstone) error
+	}{
+		{name: "RecordDeath", record: func(ts *Tombstone) error { return ts.RecordDeath(130) }},
+		{name: "RecordDeathOnce", record: func(ts *Tombstone) error { return ts.RecordDeathOnce(130) }},
+		{name: "RecordDeathWithError", record: func(ts *Tombstone) error {
+			return ts.RecordDeathWithError(130, errors.New("exit status 130"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graveyard := tempGraveyard(t)
+			ts := &Tombstone{Graveyard: graveyard, Name: "app", ExitCodeTransform: stripOffset}
+			if err := ts.RecordBirth(); err != nil {
+				t.Fatalf("failed to record birth: %v", err)
+			}
+			if err := tt.record(ts); err != nil {
+				t.Fatalf("failed to record death: %v", err)
+			}
+
+			got := mustRead(t, graveyard, "app")
+			if got.ExitCode == nil || *got.ExitCode != 2 {
+				t.Fatalf("expected transformed exit code 2, got: %s", got)
+			}
+		})
+	}
+}
+
+func TestExitCodeTransformUnset(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordDeath(t, graveyard, "app", 130)
+	if got := mustRead(t, graveyard, "app"); *got.ExitCode != 130 {
+		t.Fatalf("expected raw exit code 130, got: %s", got)
+	}
+}
+
+func TestRecordWrapperFailure(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	if err := ts.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := ts.RecordWrapperFailure(1, 143, errors.New("death dep timed out")); err != nil {
+		t.Fatalf("failed to record wrapper failure: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.WrapperExitCode == nil || *got.WrapperExitCode != 1 {
+		t.Fatalf("expected wrapper exit code 1, got: %s", got)
+	}
+	if got.ExitCode == nil || *got.ExitCode != 143 || got.Died == nil {
+		t.Fatalf("expected death with exit code 143, got: %s", got)
+	}
+	if !reflect.DeepEqual(got.Error, []string{"death dep timed out"}) {
+		t.Fatalf("expected cause to be recorded, got: %q", got.Error)
+	}
+}
+
+func TestRecordWrapperFailureBeforeStart(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ts := &Tombstone{Graveyard: graveyard, Name: "app"}
+	if err := ts.RecordWrapperFailureBeforeStart(1, errors.New("birth dep timed out")); err != nil {
+		t.Fatalf("failed to record wrapper failure: %v", err)
+	}
+
+	got := mustRead(t, graveyard, "app")
+	if got.WrapperExitCode == nil || *got.WrapperExitCode != 1 || got.Died == nil {
+		t.Fatalf("expected death with wrapper exit code 1, got: %s", got)
+	}
+	// the process never ran
+	if got.ExitCode != nil {
+		t.Fatalf("expected no exit code, got: %d", *got.ExitCode)
+	}
+	if got.Born != nil || got.NeverBorn {
+		t.Fatalf("expected not born, but not a start failure, got: %s", got)
+	}
+}
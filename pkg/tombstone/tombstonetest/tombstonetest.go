@@ -0,0 +1,67 @@
+// Package tombstonetest provides helpers for testing systems that use
+// kubexit graveyards.
+package tombstonetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/karlkfi/kubexit/pkg/tombstone"
+)
+
+// ExpectedState is the expected state of a tombstone.
+type ExpectedState struct {
+	Phase tombstone.Phase
+	// ExitCode, if set, must match the recorded exit code.
+	ExitCode *int
+}
+
+// ExitCode returns a pointer to the code, for ExpectedState.ExitCode.
+func ExitCode(code int) *int {
+	return &code
+}
+
+// AssertGraveyardState reads the graveyard and fails the test if any of the
+// expected tombstones do not match their expected state. Missing tombstones
+// are expected to be Pending. Tombstones that are not expected are ignored.
+// All mismatches are reported together, sorted by name.
+func AssertGraveyardState(t testing.TB, graveyard string, expected map[string]ExpectedState, opts ...tombstone.Option) {
+	t.Helper()
+
+	diff := DiffGraveyardState(graveyard, expected, opts...)
+	if len(diff) > 0 {
+		t.Errorf("graveyard %s does not match expected state:\n%s", graveyard, strings.Join(diff, "\n"))
+	}
+}
+
+// DiffGraveyardState returns a line for each mismatch between the graveyard
+// and the expected state, sorted by name.
+func DiffGraveyardState(graveyard string, expected map[string]ExpectedState, opts ...tombstone.Option) []string {
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diff []string
+	for _, name := range names {
+		want := expected[name]
+		ts, _ := tombstone.ReadOrDefault(graveyard, name, opts...)
+
+		got := ts.Phase()
+		if got != want.Phase {
+			diff = append(diff, fmt.Sprintf("  %s: phase: got %s, want %s", name, got, want.Phase))
+		}
+		if want.ExitCode != nil {
+			switch {
+			case ts.ExitCode == nil:
+				diff = append(diff, fmt.Sprintf("  %s: exit code: got none, want %d", name, *want.ExitCode))
+			case *ts.ExitCode != *want.ExitCode:
+				diff = append(diff, fmt.Sprintf("  %s: exit code: got %d, want %d", name, *ts.ExitCode, *want.ExitCode))
+			}
+		}
+	}
+	return diff
+}
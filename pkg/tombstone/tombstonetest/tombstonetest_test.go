@@ -0,0 +1,90 @@
+package tombstonetest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karlkfi/kubexit/pkg/tombstone"
+)
+
+// fakeTB records errors, instead of failing the test.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func testGraveyard(t *testing.T) string {
+	t.Helper()
+	graveyard, err := ioutil.TempDir("", "graveyard")
+	if err != nil {
+		t.Fatalf("failed to create graveyard: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(graveyard) })
+
+	running := &tombstone.Tombstone{Graveyard: graveyard, Name: "running"}
+	if err := running.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	failed := &tombstone.Tombstone{Graveyard: graveyard, Name: "failed"}
+	if err := failed.RecordBirth(); err != nil {
+		t.Fatalf("failed to record birth: %v", err)
+	}
+	if err := failed.RecordDeath(2); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	return graveyard
+}
+
+func TestAssertGraveyardState(t *testing.T) {
+	graveyard := testGraveyard(t)
+
+	AssertGraveyardState(t, graveyard, map[string]ExpectedState{
+		"running": {Phase: tombstone.PhaseRunning},
+		"failed":  {Phase: tombstone.PhaseFailed, ExitCode: ExitCode(2)},
+		"missing": {Phase: tombstone.PhasePending},
+	})
+}
+
+func TestDiffGraveyardState(t *testing.T) {
+	graveyard := testGraveyard(t)
+
+	diff := DiffGraveyardState(graveyard, map[string]ExpectedState{
+		"running": {Phase: tombstone.PhaseSucceeded, ExitCode: ExitCode(0)},
+		"failed":  {Phase: tombstone.PhaseFailed, ExitCode: ExitCode(1)},
+		"missing": {Phase: tombstone.PhaseRunning},
+	})
+
+	// sorted by name
+	want := []string{
+		"  failed: exit code: got 2, want 1",
+		"  missing: phase: got Pending, want Running",
+		"  running: phase: got Running, want Succeeded",
+		"  running: exit code: got none, want 0",
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("expected diff:\n%q\ngot:\n%q", want, diff)
+	}
+}
+
+func TestAssertGraveyardStateMismatch(t *testing.T) {
+	graveyard := testGraveyard(t)
+
+	tb := &fakeTB{TB: t}
+	AssertGraveyardState(tb, graveyard, map[string]ExpectedState{
+		"running": {Phase: tombstone.PhaseFailed},
+		"failed":  {Phase: tombstone.PhaseRunning},
+	})
+	// reported together
+	if len(tb.errors) != 1 {
+		t.Fatalf("expected one error, got: %q", tb.errors)
+	}
+}
@@ -0,0 +1,105 @@
+package tombstone
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultCgroupRoot is where the container cgroup is usually mounted.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// LoadResourceUsage populates PeakMemoryBytes and CPUSeconds from the cgroup
+// mounted at cgroupRoot. Both cgroup v2 (unified) and v1 layouts are
+// supported. Each field is read independently: fields that can't be read
+// are left unset, and their errors are joined in the returned error.
+// The tombstone is not written.
+func (t *Tombstone) LoadResourceUsage(cgroupRoot string) error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return t.loadResourceUsageV2(cgroupRoot)
+	}
+	return t.loadResourceUsageV1(cgroupRoot)
+}
+
+func (t *Tombstone) loadResourceUsageV2(cgroupRoot string) error {
+	var errs []string
+
+	// memory.peak requires kernel 5.19+
+	peak, err := readUintFile(filepath.Join(cgroupRoot, "memory.peak"))
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to read peak memory: %v", err))
+	} else {
+		t.PeakMemoryBytes = &peak
+	}
+
+	usec, err := readCPUStat(filepath.Join(cgroupRoot, "cpu.stat"), "usage_usec")
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to read cpu usage: %v", err))
+	} else {
+		seconds := float64(usec) / 1e6
+		t.CPUSeconds = &seconds
+	}
+	return usageError(errs)
+}
+
+func (t *Tombstone) loadResourceUsageV1(cgroupRoot string) error {
+	var errs []string
+
+	peak, err := readUintFile(filepath.Join(cgroupRoot, "memory", "memory.max_usage_in_bytes"))
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to read peak memory: %v", err))
+	} else {
+		t.PeakMemoryBytes = &peak
+	}
+
+	nsec, err := readUintFile(filepath.Join(cgroupRoot, "cpuacct", "cpuacct.usage"))
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to read cpu usage: %v", err))
+	} else {
+		seconds := float64(nsec) / 1e9
+		t.CPUSeconds = &seconds
+	}
+	return usageError(errs)
+}
+
+// usageError joins the errors from reading each field, if any.
+func usageError(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+func readUintFile(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readCPUStat reads a single key from a flat keyed cgroup file.
+func readCPUStat(path, key string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("key not found: %s", key)
+}
@@ -0,0 +1,112 @@
+package tombstone
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCgroupFiles writes the files, relative to a new cgroup root.
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := tempGraveyard(t)
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestLoadResourceUsageV2(t *testing.T) {
+	root := writeCgroupFiles(t, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"memory.peak":        "1048576\n",
+		"cpu.stat":           "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+	})
+
+	ts := &Tombstone{}
+	if err := ts.LoadResourceUsage(root); err != nil {
+		t.Fatalf("failed to load resource usage: %v", err)
+	}
+	if ts.PeakMemoryBytes == nil || *ts.PeakMemoryBytes != 1048576 {
+		t.Errorf("expected peak memory 1048576, got: %v", ts.PeakMemoryBytes)
+	}
+	if ts.CPUSeconds == nil || *ts.CPUSeconds != 2.5 {
+		t.Errorf("expected cpu seconds 2.5, got: %v", ts.CPUSeconds)
+	}
+}
+
+func TestLoadResourceUsageV2NoPeak(t *testing.T) {
+	// memory.peak is missing before kernel 5.19
+	root := writeCgroupFiles(t, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"cpu.stat":           "usage_usec 1000000\n",
+	})
+
+	ts := &Tombstone{}
+	err := ts.LoadResourceUsage(root)
+	if err == nil || !strings.Contains(err.Error(), "peak memory") {
+		t.Fatalf("expected peak memory error, got: %v", err)
+	}
+	if ts.PeakMemoryBytes != nil {
+		t.Errorf("expected peak memory unset, got: %v", *ts.PeakMemoryBytes)
+	}
+	if ts.CPUSeconds == nil || *ts.CPUSeconds != 1 {
+		t.Errorf("expected cpu seconds 1 to be read independently, got: %v", ts.CPUSeconds)
+	}
+}
+
+func TestLoadResourceUsageV2NothingReadable(t *testing.T) {
+	root := writeCgroupFiles(t, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"cpu.stat":           "user_usec 1000000\n",
+	})
+
+	ts := &Tombstone{}
+	err := ts.LoadResourceUsage(root)
+	if err == nil || !strings.Contains(err.Error(), "peak memory") || !strings.Contains(err.Error(), "cpu usage") {
+		t.Fatalf("expected both errors joined, got: %v", err)
+	}
+	if ts.PeakMemoryBytes != nil || ts.CPUSeconds != nil {
+		t.Errorf("expected fields unset, got: %v, %v", ts.PeakMemoryBytes, ts.CPUSeconds)
+	}
+}
+
+func TestLoadResourceUsageV1(t *testing.T) {
+	root := writeCgroupFiles(t, map[string]string{
+		"memory/memory.max_usage_in_bytes": "2048\n",
+		"cpuacct/cpuacct.usage":            "1500000000\n",
+	})
+
+	ts := &Tombstone{}
+	if err := ts.LoadResourceUsage(root); err != nil {
+		t.Fatalf("failed to load resource usage: %v", err)
+	}
+	if ts.PeakMemoryBytes == nil || *ts.PeakMemoryBytes != 2048 {
+		t.Errorf("expected peak memory 2048, got: %v", ts.PeakMemoryBytes)
+	}
+	if ts.CPUSeconds == nil || *ts.CPUSeconds != 1.5 {
+		t.Errorf("expected cpu seconds 1.5, got: %v", ts.CPUSeconds)
+	}
+}
+
+func TestLoadResourceUsageV1NoMemory(t *testing.T) {
+	root := writeCgroupFiles(t, map[string]string{
+		"cpuacct/cpuacct.usage": "1000000000\n",
+	})
+
+	ts := &Tombstone{}
+	if err := ts.LoadResourceUsage(root); err == nil {
+		t.Fatalf("expected peak memory error")
+	}
+	if ts.CPUSeconds == nil || *ts.CPUSeconds != 1 {
+		t.Errorf("expected cpu seconds 1 to be read independently, got: %v", ts.CPUSeconds)
+	}
+}
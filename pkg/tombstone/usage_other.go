@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package tombstone
+
+import "errors"
+
+// DefaultCgroupRoot is where the container cgroup is usually mounted.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// LoadResourceUsage is only supported on Linux.
+// Elsewhere, the fields are left unset and an error is returned.
+func (t *Tombstone) LoadResourceUsage(cgroupRoot string) error {
+	return errors.New("resource usage not supported on this platform")
+}
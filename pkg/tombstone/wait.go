@@ -0,0 +1,136 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// condition describes a tombstone state to wait for.
+type condition struct {
+	// name of the state, for logging
+	name  string
+	match func(*Tombstone) bool
+}
+
+var (
+	born = condition{name: "born", match: func(t *Tombstone) bool { return t.Born != nil }}
+	died = condition{name: "died", match: func(t *Tombstone) bool { return t.Died != nil }}
+	// dying includes the dead, in case terminating wasn't recorded
+	dying = condition{name: "terminating", match: func(t *Tombstone) bool { return t.Terminating != nil || t.Died != nil }}
+	// cascading deaths are those that should shut down dependents
+	cascading = condition{name: "died (cascading)", match: func(t *Tombstone) bool { return t.ShouldCascade() }}
+)
+
+// await blocks until at least quorum of the named tombstones match the
+// condition, or the context is done. Tombstones that already match count
+// immediately. A single graveyard watcher is used for all names.
+// If the graveyard does not exist, it is created, so that it can be watched.
+//
+// The names that matched and the names still pending are returned, in the
+// order requested. If the context is done first, its error is also returned.
+func await(ctx context.Context, graveyard string, names []string, quorum int, config options, cond condition) ([]string, []string, error) {
+	if quorum > len(names) {
+		return nil, names, fmt.Errorf("quorum exceeds number of names: %d > %d", quorum, len(names))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var lock sync.Mutex
+	pending := map[string]struct{}{}
+	for _, name := range names {
+		pending[name] = struct{}{}
+	}
+	matched := 0
+
+	// split returns the matched and pending names, in the order requested
+	split := func() ([]string, []string) {
+		var yes, no []string
+		for _, name := range names {
+			if _, ok := pending[name]; ok {
+				no = append(no, name)
+			} else {
+				yes = append(yes, name)
+			}
+		}
+		return yes, no
+	}
+
+	if quorum <= 0 {
+		return nil, names, nil
+	}
+
+	// check removes the name from pending, if it matches, and cancels the
+	// context when the quorum is reached.
+	check := func(name string) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if matched >= quorum {
+			// already done
+			return
+		}
+		if _, ok := pending[name]; !ok {
+			return
+		}
+		ts, err := Read(graveyard, name, withOptions(config))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Error: failed to read tombstone: %v\n", err)
+			}
+			return
+		}
+		if !cond.match(ts) {
+			return
+		}
+		if !config.matches(ts) {
+			log.Printf("Ignoring tombstone from other pod generation: %s (%s)\n", name, ts.PodGeneration)
+			return
+		}
+		log.Printf("Tombstone %s: %s\n", cond.name, name)
+		delete(pending, name)
+		matched++
+		if matched >= quorum {
+			cancel()
+		}
+	}
+
+	err := os.MkdirAll(graveyard, os.ModePerm)
+	if err != nil {
+		return nil, names, fmt.Errorf("failed to create graveyard: %v", err)
+	}
+
+	// watch before checking existing tombstones, so no changes are missed
+	err = Watch(ctx, graveyard, func(event fsnotify.Event) {
+		if event.Op&fsnotify.Create != fsnotify.Create && event.Op&fsnotify.Write != fsnotify.Write {
+			// ignore other events
+			return
+		}
+		check(filepath.Base(event.Name))
+	}, withOptions(config))
+	if err != nil {
+		return nil, names, fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+
+	for _, name := range names {
+		check(name)
+	}
+
+	<-ctx.Done()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	yes, no := split()
+	if matched >= quorum {
+		return yes, no, nil
+	}
+	return yes, no, ctx.Err()
+}
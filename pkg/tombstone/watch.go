@@ -0,0 +1,374 @@
+package tombstone
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+type EventHandler func(fsnotify.Event)
+
+// LoggingEventHandler is an example EventHandler that logs fsnotify events
+func LoggingEventHandler(event fsnotify.Event) {
+	if event.Op&fsnotify.Create == fsnotify.Create {
+		log.Printf("Tombstone Watch: file created: %s\n", event.Name)
+	}
+	if event.Op&fsnotify.Remove == fsnotify.Remove {
+		log.Printf("Tombstone Watch: file removed: %s\n", event.Name)
+	}
+	if event.Op&fsnotify.Write == fsnotify.Write {
+		log.Printf("Tombstone Watch: file modified: %s\n", event.Name)
+	}
+	if event.Op&fsnotify.Rename == fsnotify.Rename {
+		log.Printf("Tombstone Watch: file renamed: %s\n", event.Name)
+	}
+	if event.Op&fsnotify.Chmod == fsnotify.Chmod {
+		log.Printf("Tombstone Watch: file chmoded: %s\n", event.Name)
+	}
+}
+
+// Watch a graveyard and call the eventHandler (asyncronously) when an
+// event happens. When the supplied context is canceled, watching will stop.
+// If sharded, the graveyard subdirectories are also watched, but events for
+// the subdirectories themselves are not passed to the eventHandler.
+// With WithReplay, existing tombstones are also passed to the eventHandler.
+func Watch(ctx context.Context, graveyard string, eventHandler EventHandler, opts ...Option) error {
+	config := newOptions(opts)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %v", err)
+	}
+
+	// nil channel blocks forever, if not replaying
+	var replayCh chan string
+	if config.replay {
+		replayCh = make(chan string, DefaultSubscribeBuffer)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		dispatch, stopDispatch := newDispatcher(eventHandler, config.concurrency)
+		defer stopDispatch()
+
+		// nil channel blocks forever, if not ticking
+		var tickCh <-chan time.Time
+		if config.tick > 0 && config.onTick != nil {
+			ticker := time.NewTicker(config.tick)
+			defer ticker.Stop()
+			tickCh = ticker.C
+		}
+
+		debouncer := newDebouncer(config.debounce)
+		defer debouncer.stop()
+
+		handle := func(event fsnotify.Event) {
+			if !config.wants(event) {
+				return
+			}
+			if isSymlink(event.Name) && !followSymlink(event.Name, config) {
+				return
+			}
+			if config.debounce > 0 {
+				debouncer.add(event)
+				return
+			}
+			dispatch(event)
+		}
+
+		// names with live events during replay, which replay must skip,
+		// because the live event already reflects the newer state
+		var live map[string]struct{}
+		if config.replay {
+			live = map[string]struct{}{}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				if config.flushOnStop {
+					for _, event := range debouncer.drain() {
+						dispatch(event)
+					}
+				}
+				log.Printf("Tombstone Watch(%s): done\n", graveyard)
+				return
+			case now := <-tickCh:
+				config.onTick(now)
+			case name, ok := <-replayCh:
+				if !ok {
+					replayCh = nil
+					live = nil
+					if config.onReady != nil {
+						config.onReady()
+					}
+					continue
+				}
+				if _, ok := live[name]; ok {
+					continue
+				}
+				handle(fsnotify.Event{
+					Name: replayPath(graveyard, name, config),
+					Op:   fsnotify.Create,
+				})
+			case <-debouncer.ch:
+				for _, event := range debouncer.drain() {
+					dispatch(event)
+				}
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if live != nil {
+					live[filepath.Base(event.Name)] = struct{}{}
+				}
+				if config.shard != nil && event.Op&fsnotify.Create == fsnotify.Create {
+					isDir, events, err := addShardDir(watcher, event.Name)
+					if err != nil {
+						log.Printf("Tombstone Watch(%s): error: %v\n", graveyard, err)
+					}
+					if isDir {
+						for _, event := range events {
+							handle(event)
+						}
+						continue
+					}
+				}
+				handle(event)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Tombstone Watch(%s): error: %v\n", graveyard, err)
+				// TODO: wrap ctx with WithCancel and cancel on terminal errors, if any
+			}
+		}
+	}()
+
+	err = watcher.Add(graveyard)
+	if err != nil {
+		return fmt.Errorf("failed to add watcher: %v", err)
+	}
+	if config.shard != nil {
+		err = addShardDirs(watcher, graveyard)
+		if err != nil {
+			return err
+		}
+	}
+	if config.replay {
+		// list after watching, so no changes are missed
+		go replayNames(ctx, graveyard, config, replayCh)
+	}
+	return nil
+}
+
+// replayNames sends the names of the existing tombstones, then closes names.
+func replayNames(ctx context.Context, graveyard string, config options, names chan<- string) {
+	defer close(names)
+
+	existing, err := listNames(graveyard, config)
+	if err != nil {
+		log.Printf("Tombstone Watch(%s): replay error: %v\n", graveyard, err)
+		return
+	}
+	for _, name := range existing {
+		select {
+		case names <- name:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// replayPath returns the path of an existing tombstone.
+func replayPath(graveyard, name string, config options) string {
+	if config.shard != nil {
+		return filepath.Join(graveyard, config.shard(name), name)
+	}
+	return filepath.Join(graveyard, name)
+}
+
+// WithTick makes Watch call onTick on an interval, even if nothing changed,
+// so watchers can tell "alive but idle" from "dead". Ticks are called from
+// the same goroutine as the event handler, so they never run concurrently
+// with, or reorder, events (unless WithConcurrency is used).
+func WithTick(interval time.Duration, onTick func(time.Time)) Option {
+	return func(c *options) {
+		c.tick = interval
+		c.onTick = onTick
+	}
+}
+
+// WithReplay makes Watch call the event handler with a create event for each
+// tombstone that already exists, so watchers don't need to read the
+// graveyard first. Replay happens in the background, after Watch returns,
+// so large graveyards don't delay the caller. Tombstones with live events
+// during replay are not replayed. If not nil, onReady is called, from the
+// same goroutine as the event handler (unless WithConcurrency is used), when
+// replay is complete.
+func WithReplay(onReady func()) Option {
+	return func(c *options) {
+		c.replay = true
+		c.onReady = onReady
+	}
+}
+
+// WithDebounce makes Watch wait until no events have happened for the
+// duration before calling the event handler. Events for the same file are
+// coalesced into one event, with the operations combined, in the order the
+// files were first seen. Pending events are dropped when watching stops,
+// unless WithFlushOnStop is used.
+func WithDebounce(d time.Duration) Option {
+	return func(c *options) {
+		c.debounce = d
+	}
+}
+
+// WithFlushOnStop makes Watch call the event handler with any pending
+// debounced events when the context is canceled, before watching stops, so
+// the last change before shutdown isn't lost.
+func WithFlushOnStop(flush bool) Option {
+	return func(c *options) {
+		c.flushOnStop = flush
+	}
+}
+
+// WithNameFilter makes Watch ignore events for tombstones without one of the
+// names. Without names, nothing is filtered.
+func WithNameFilter(names ...string) Option {
+	return func(c *options) {
+		if len(names) == 0 {
+			c.names = nil
+			return
+		}
+		c.names = map[string]struct{}{}
+		for _, name := range names {
+			c.names[name] = struct{}{}
+		}
+	}
+}
+
+// WithConcurrency makes Watch call the event handler from n goroutines.
+// Events for the same file are always handled by the same goroutine, so they
+// stay in order. Less than 2 means events are handled one at a time.
+func WithConcurrency(n int) Option {
+	return func(c *options) {
+		c.concurrency = n
+	}
+}
+
+// wants returns true if the event passes the name filter.
+func (o options) wants(event fsnotify.Event) bool {
+	if o.names == nil {
+		return true
+	}
+	_, ok := o.names[filepath.Base(event.Name)]
+	return ok
+}
+
+// isSymlink returns true if the path exists and is a symlink.
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// newDispatcher returns a function that calls the handler, either inline or
+// on n worker goroutines, partitioned by file name. Stop waits for the
+// workers to finish handling dispatched events.
+func newDispatcher(handler EventHandler, n int) (dispatch func(fsnotify.Event), stop func()) {
+	if n < 2 {
+		return handler, func() {}
+	}
+
+	var wg sync.WaitGroup
+	workers := make([]chan fsnotify.Event, n)
+	for i := range workers {
+		workers[i] = make(chan fsnotify.Event, DefaultSubscribeBuffer)
+		wg.Add(1)
+		go func(events <-chan fsnotify.Event) {
+			defer wg.Done()
+			for event := range events {
+				handler(event)
+			}
+		}(workers[i])
+	}
+
+	dispatch = func(event fsnotify.Event) {
+		hash := fnv.New32a()
+		hash.Write([]byte(event.Name))
+		workers[hash.Sum32()%uint32(n)] <- event
+	}
+	stop = func() {
+		for _, events := range workers {
+			close(events)
+		}
+		wg.Wait()
+	}
+	return dispatch, stop
+}
+
+// debouncer coalesces events per file until a quiet period elapses.
+type debouncer struct {
+	delay   time.Duration
+	timer   *time.Timer
+	ch      <-chan time.Time
+	order   []string
+	pending map[string]fsnotify.Event
+}
+
+func newDebouncer(delay time.Duration) *debouncer {
+	return &debouncer{
+		delay:   delay,
+		pending: map[string]fsnotify.Event{},
+	}
+}
+
+// add an event and restart the quiet period.
+func (d *debouncer) add(event fsnotify.Event) {
+	if prev, ok := d.pending[event.Name]; ok {
+		event.Op |= prev.Op
+	} else {
+		d.order = append(d.order, event.Name)
+	}
+	d.pending[event.Name] = event
+
+	if d.timer == nil {
+		d.timer = time.NewTimer(d.delay)
+	} else {
+		if !d.timer.Stop() {
+			// drain, if fired but not received
+			select {
+			case <-d.timer.C:
+			default:
+			}
+		}
+		d.timer.Reset(d.delay)
+	}
+	d.ch = d.timer.C
+}
+
+// drain returns the pending events, in the order first seen, and clears them.
+func (d *debouncer) drain() []fsnotify.Event {
+	events := make([]fsnotify.Event, 0, len(d.order))
+	for _, name := range d.order {
+		events = append(events, d.pending[name])
+	}
+	d.order = nil
+	d.pending = map[string]fsnotify.Event{}
+	d.ch = nil
+	return events
+}
+
+func (d *debouncer) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
@@ -0,0 +1,318 @@
+package tombstone
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchEvents watches the graveyard until the test ends and returns the
+// events handled, except for hidden files (ex: atomic write temp files).
+func watchEvents(t *testing.T, graveyard string, opts ...Option) <-chan fsnotify.Event {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	events := make(chan fsnotify.Event, 100)
+	err := Watch(ctx, graveyard, visibleEvents(events), opts...)
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	return events
+}
+
+// visibleEvents returns a handler that sends the events, except for hidden
+// files, to the channel.
+func visibleEvents(events chan<- fsnotify.Event) EventHandler {
+	return func(event fsnotify.Event) {
+		if !isHidden(filepath.Base(event.Name)) {
+			events <- event
+		}
+	}
+}
+
+// nextEvent returns the next event, or fails the test after a timeout.
+func nextEvent(t *testing.T, events <-chan fsnotify.Event) fsnotify.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for event")
+		return fsnotify.Event{}
+	}
+}
+
+// expectNoEvent fails the test if an event is handled within the duration.
+func expectNoEvent(t *testing.T, events <-chan fsnotify.Event, d time.Duration) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Fatalf("expected no event, got: %v", event)
+	case <-time.After(d):
+	}
+}
+
+func TestWatchTick(t *testing.T) {
+	ticks := make(chan time.Time, 100)
+	watchEvents(t, tempGraveyard(t), WithTick(10*time.Millisecond, func(now time.Time) {
+		ticks <- now
+	}))
+
+	// while idle
+	var prev time.Time
+	for i := 0; i < 3; i++ {
+		select {
+		case now := <-ticks:
+			if !now.After(prev) {
+				t.Fatalf("expected ticks in order, got %s after %s", now, prev)
+			}
+			prev = now
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for tick %d", i)
+		}
+	}
+}
+
+func TestWatchTickStops(t *testing.T) {
+	ticks := make(chan time.Time, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Watch(ctx, tempGraveyard(t), func(fsnotify.Event) {}, WithTick(10*time.Millisecond, func(now time.Time) {
+		ticks <- now
+	}))
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	<-ticks
+	cancel()
+
+	// at most one tick may have been in flight
+	time.Sleep(50 * time.Millisecond)
+	for len(ticks) > 0 {
+		<-ticks
+	}
+	select {
+	case <-ticks:
+		t.Fatalf("expected no ticks after cancel")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	events := watchEvents(t, graveyard, WithDebounce(100*time.Millisecond))
+
+	path := filepath.Join(graveyard, "app")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	defer file.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := file.WriteString("{}\n"); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	// coalesced, with the operations combined
+	event := nextEvent(t, events)
+	if event.Name != path {
+		t.Fatalf("expected event for %s, got: %v", path, event)
+	}
+	if event.Op&fsnotify.Create == 0 || event.Op&fsnotify.Write == 0 {
+		t.Fatalf("expected create and write operations, got: %v", event.Op)
+	}
+	expectNoEvent(t, events, 200*time.Millisecond)
+}
+
+func TestWatchNameFilter(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	events := watchEvents(t, graveyard, WithNameFilter("a"))
+
+	mustRecordBirth(t, graveyard, "b")
+	a := mustRecordBirth(t, graveyard, "a")
+
+	if event := nextEvent(t, events); event.Name != a.Path() {
+		t.Fatalf("expected only events for a, got: %v", event)
+	}
+}
+
+func TestWatchNameFilterEmpty(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	events := watchEvents(t, graveyard, WithNameFilter())
+
+	b := mustRecordBirth(t, graveyard, "b")
+	if event := nextEvent(t, events); event.Name != b.Path() {
+		t.Fatalf("expected event for b, got: %v", event)
+	}
+}
+
+// differentWorkers returns two names that are dispatched to different
+// workers, out of n.
+func differentWorkers(n int) (string, string) {
+	worker := func(name string) uint32 {
+		hash := fnv.New32a()
+		hash.Write([]byte(name))
+		return hash.Sum32() % uint32(n)
+	}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("name-%d", i)
+		if worker(name) != worker("name-0") {
+			return "name-0", name
+		}
+	}
+}
+
+func TestDispatcherConcurrency(t *testing.T) {
+	slow, fast := differentWorkers(4)
+
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	dispatch, stop := newDispatcher(func(event fsnotify.Event) {
+		if event.Name == slow {
+			<-release
+		}
+		handled <- event.Name
+	}, 4)
+
+	dispatch(fsnotify.Event{Name: slow})
+	dispatch(fsnotify.Event{Name: fast})
+
+	// not blocked by the slow handler
+	select {
+	case name := <-handled:
+		if name != fast {
+			t.Fatalf("expected %s handled first, got: %s", fast, name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", fast)
+	}
+	close(release)
+	stop()
+	if name := <-handled; name != slow {
+		t.Fatalf("expected %s handled before stop returned, got: %s", slow, name)
+	}
+}
+
+func TestDispatcherOrderPerFile(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string][]fsnotify.Op{}
+	dispatch, stop := newDispatcher(func(event fsnotify.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[event.Name] = append(got[event.Name], event.Op)
+	}, 4)
+
+	names := []string{"a", "b", "c", "d", "e"}
+	for i := 1; i <= 50; i++ {
+		for _, name := range names {
+			// the op is a sequence number
+			dispatch(fsnotify.Event{Name: name, Op: fsnotify.Op(i)})
+		}
+	}
+	stop()
+
+	for _, name := range names {
+		if len(got[name]) != 50 {
+			t.Fatalf("expected 50 events for %s, got: %d", name, len(got[name]))
+		}
+		for i, op := range got[name] {
+			if op != fsnotify.Op(i+1) {
+				t.Fatalf("expected events for %s in order, got: %v", name, got[name])
+			}
+		}
+	}
+}
+
+func TestWatchReplay(t *testing.T) {
+	for _, shard := range []ShardFunc{nil, HashShard} {
+		graveyard := tempGraveyard(t)
+		var paths []string
+		for _, name := range []string{"a", "b"} {
+			ts := &Tombstone{Graveyard: graveyard, Name: name, Shard: shard}
+			if err := ts.RecordBirth(); err != nil {
+				t.Fatalf("failed to record birth: %v", err)
+			}
+			paths = append(paths, ts.Path())
+		}
+
+		ready := make(chan struct{})
+		events := watchEvents(t, graveyard, WithShard(shard), WithReplay(func() { close(ready) }))
+
+		for _, path := range paths {
+			event := nextEvent(t, events)
+			if event.Name != path || event.Op != fsnotify.Create {
+				t.Fatalf("expected replayed create of %s, got: %v", path, event)
+			}
+		}
+		select {
+		case <-ready:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for ready")
+		}
+		// called from the same goroutine, so after the replayed events
+		if len(events) != 0 {
+			t.Fatalf("expected no events after replay, got: %v", <-events)
+		}
+	}
+}
+
+func TestWatchReplayEmpty(t *testing.T) {
+	ready := make(chan struct{})
+	watchEvents(t, tempGraveyard(t), WithReplay(func() { close(ready) }))
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for ready")
+	}
+}
+
+func TestWatchWithoutReplay(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "existing")
+
+	expectNoEvent(t, watchEvents(t, graveyard), 100*time.Millisecond)
+}
+
+func TestWatchFlushOnStop(t *testing.T) {
+	tests := []struct {
+		name  string
+		flush bool
+	}{
+		{name: "flushed", flush: true},
+		{name: "dropped", flush: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graveyard := tempGraveyard(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := make(chan fsnotify.Event, 100)
+			err := Watch(ctx, graveyard, visibleEvents(events), WithDebounce(time.Hour), WithFlushOnStop(tt.flush))
+			if err != nil {
+				t.Fatalf("failed to watch: %v", err)
+			}
+
+			ts := mustRecordBirth(t, graveyard, "app")
+			// pending, until the quiet period elapses
+			expectNoEvent(t, events, 100*time.Millisecond)
+			cancel()
+
+			if !tt.flush {
+				expectNoEvent(t, events, 100*time.Millisecond)
+				return
+			}
+			if event := nextEvent(t, events); event.Name != ts.Path() {
+				t.Fatalf("expected pending event for %s, got: %v", ts.Path(), event)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// liveness is the state of a tombstone, as tracked by a Watcher.
+type liveness int
+
+const (
+	// unborn tombstones are neither alive nor dead.
+	unborn liveness = iota
+	alive
+	dead
+)
+
+func livenessOf(t *Tombstone) liveness {
+	switch {
+	case t.Died != nil || t.NeverBorn:
+		return dead
+	case t.Born != nil:
+		return alive
+	default:
+		return unborn
+	}
+}
+
+// Watcher watches a graveyard and tracks the state of each tombstone, so it
+// can be queried without reading the graveyard. Existing tombstones are
+// replayed in the background, after NewWatcher returns. Tombstones from
+// other pod generations (see WithPodGeneration) are ignored.
+//
+// With WithEventHandler, events are also passed to the handler, after the
+// state is updated. Delivery can be paused and resumed.
+type Watcher struct {
+	graveyard string
+	config    options
+	ready     chan struct{}
+
+	// deliverLock is held while calling the handler, to keep events in order
+	deliverLock  sync.Mutex
+	paused       bool
+	pausedOrder  []string
+	pausedEvents map[string]fsnotify.Event
+
+	lock      sync.RWMutex
+	states    map[string]liveness
+	lastEvent map[string]time.Time
+	alive     int
+	dead      int
+}
+
+// NewWatcher starts watching a graveyard. When the supplied context is
+// canceled, watching will stop.
+func NewWatcher(ctx context.Context, graveyard string, opts ...Option) (*Watcher, error) {
+	w := &Watcher{
+		graveyard: graveyard,
+		config:    newOptions(opts),
+		ready:     make(chan struct{}),
+		states:    map[string]liveness{},
+		lastEvent: map[string]time.Time{},
+	}
+	if w.config.pauseBuffer <= 0 {
+		w.config.pauseBuffer = DefaultSubscribeBuffer
+	}
+
+	err := Watch(ctx, graveyard, w.handle, withOptions(w.config), WithReplay(func() {
+		close(w.ready)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Ready returns a channel that is closed when the existing tombstones have
+// been replayed.
+func (w *Watcher) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// Counts returns the number of tombstones that are alive (born, not dead)
+// and dead (died or never born).
+func (w *Watcher) Counts() (alive, dead int) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.alive, w.dead
+}
+
+// LastEventTime returns when the watcher last saw an event for the named
+// tombstone (or replayed it), so stalled writers can be detected.
+// Returns false if the tombstone was removed or never seen.
+func (w *Watcher) LastEventTime(name string) (time.Time, bool) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	t, ok := w.lastEvent[name]
+	return t, ok
+}
+
+// WithEventHandler makes the Watcher pass each event to the handler, after
+// tracking it.
+func WithEventHandler(handler EventHandler) Option {
+	return func(c *options) {
+		c.handler = handler
+	}
+}
+
+// WithPauseBuffer sets how many files the Watcher buffers events for while
+// paused. Events for other files are dropped, but still tracked.
+// Default: DefaultSubscribeBuffer.
+func WithPauseBuffer(n int) Option {
+	return func(c *options) {
+		c.pauseBuffer = n
+	}
+}
+
+// Pause stops passing events to the handler, until Resume. Events are still
+// tracked, and are buffered for delivery on Resume. Pause must not be called
+// from the handler.
+func (w *Watcher) Pause() {
+	w.deliverLock.Lock()
+	defer w.deliverLock.Unlock()
+	if w.paused {
+		return
+	}
+	w.paused = true
+	w.pausedEvents = map[string]fsnotify.Event{}
+}
+
+// Resume passes the events buffered while paused to the handler, in the
+// order the files were first seen, with the operations for each file
+// combined into one event, and then resumes delivery. Resume must not be
+// called from the handler.
+func (w *Watcher) Resume() {
+	w.deliverLock.Lock()
+	defer w.deliverLock.Unlock()
+	if !w.paused {
+		return
+	}
+	for _, name := range w.pausedOrder {
+		w.config.handler(w.pausedEvents[name])
+	}
+	w.paused = false
+	w.pausedOrder = nil
+	w.pausedEvents = nil
+}
+
+func (w *Watcher) handle(event fsnotify.Event) {
+	name := filepath.Base(event.Name)
+	if isHidden(name) || isSidecar(name) {
+		return
+	}
+	w.track(name, event)
+	if w.config.handler != nil {
+		w.deliver(event)
+	}
+}
+
+func (w *Watcher) deliver(event fsnotify.Event) {
+	w.deliverLock.Lock()
+	defer w.deliverLock.Unlock()
+
+	if !w.paused {
+		w.config.handler(event)
+		return
+	}
+	if prev, ok := w.pausedEvents[event.Name]; ok {
+		event.Op |= prev.Op
+	} else {
+		if len(w.pausedOrder) >= w.config.pauseBuffer {
+			log.Printf("Tombstone Watcher(%s): pause buffer full: dropped event: %s\n", w.graveyard, event)
+			return
+		}
+		w.pausedOrder = append(w.pausedOrder, event.Name)
+	}
+	w.pausedEvents[event.Name] = event
+}
+
+// track updates the tombstone state for the event.
+func (w *Watcher) track(name string, event fsnotify.Event) {
+	w.seen(name, time.Now())
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		w.remove(name)
+		return
+	}
+	if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+		return
+	}
+
+	ts, err := Read(w.graveyard, name, withOptions(w.config))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			w.remove(name)
+		} else {
+			log.Printf("Error: failed to read tombstone: %v\n", err)
+		}
+		return
+	}
+	if !w.config.matches(ts) {
+		w.remove(name)
+		return
+	}
+	w.update(name, livenessOf(ts))
+}
+
+func (w *Watcher) update(name string, state liveness) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if prev, ok := w.states[name]; ok {
+		w.count(prev, -1)
+	}
+	w.states[name] = state
+	w.count(state, 1)
+}
+
+func (w *Watcher) seen(name string, now time.Time) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.lastEvent[name] = now
+}
+
+func (w *Watcher) remove(name string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	delete(w.lastEvent, name)
+	if prev, ok := w.states[name]; ok {
+		w.count(prev, -1)
+		delete(w.states, name)
+	}
+}
+
+// count adds delta to the counter for the state. Caller must hold the lock.
+func (w *Watcher) count(state liveness, delta int) {
+	switch state {
+	case alive:
+		w.alive += delta
+	case dead:
+		w.dead += delta
+	}
+}
@@ -0,0 +1,190 @@
+package tombstone
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// newTestWatcher starts a watcher until the test ends and waits for it to
+// be ready.
+func newTestWatcher(t *testing.T, graveyard string, opts ...Option) *Watcher {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	w, err := NewWatcher(ctx, graveyard, opts...)
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	select {
+	case <-w.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for watcher to be ready")
+	}
+	return w
+}
+
+// awaitCounts waits for the watcher to count the alive and dead tombstones.
+func awaitCounts(t *testing.T, w *Watcher, wantAlive, wantDead int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		alive, dead := w.Counts()
+		if alive == wantAlive && dead == wantDead {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d alive and %d dead, got: %d alive and %d dead", wantAlive, wantDead, alive, dead)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcherCounts(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	a := mustRecordBirth(t, graveyard, "a")
+	b := mustRecordDeath(t, graveyard, "b", 0)
+	never := &Tombstone{Graveyard: graveyard, Name: "never"}
+	if err := never.RecordNeverBorn(127, nil); err != nil {
+		t.Fatalf("failed to record never born: %v", err)
+	}
+	pending := &Tombstone{Graveyard: graveyard, Name: "pending"}
+	if err := pending.Write(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	w := newTestWatcher(t, graveyard)
+	// replayed; never born counts as dead and pending as neither
+	awaitCounts(t, w, 1, 2)
+
+	mustRecordBirth(t, graveyard, "c")
+	awaitCounts(t, w, 2, 2)
+
+	if err := a.RecordDeath(0); err != nil {
+		t.Fatalf("failed to record death: %v", err)
+	}
+	awaitCounts(t, w, 1, 3)
+
+	if err := os.Remove(b.Path()); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	awaitCounts(t, w, 1, 2)
+}
+
+func TestWatcherPodGeneration(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	for name, generation := range map[string]string{"current": "2", "old": "1"} {
+		ts := &Tombstone{Graveyard: graveyard, Name: name, PodGeneration: generation}
+		if err := ts.RecordBirth(); err != nil {
+			t.Fatalf("failed to record birth: %v", err)
+		}
+	}
+
+	w := newTestWatcher(t, graveyard, WithPodGeneration("2"))
+	awaitCounts(t, w, 1, 0)
+}
+
+func TestWatcherLastEventTime(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	before := time.Now()
+	existing := mustRecordBirth(t, graveyard, "existing")
+
+	w := newTestWatcher(t, graveyard)
+	// replayed
+	replayed, ok := w.LastEventTime("existing")
+	if !ok || replayed.Before(before) {
+		t.Fatalf("expected replayed event time after %s, got: %s, %v", before, replayed, ok)
+	}
+	if _, ok := w.LastEventTime("missing"); ok {
+		t.Fatalf("expected no event time for a tombstone never seen")
+	}
+
+	if err := existing.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	awaitEventTime(t, w, "existing", func(last time.Time, ok bool) bool {
+		return ok && last.After(replayed)
+	})
+
+	if err := os.Remove(existing.Path()); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	awaitEventTime(t, w, "existing", func(_ time.Time, ok bool) bool {
+		return !ok
+	})
+}
+
+// awaitEventTime waits for the last event time of the named tombstone to
+// match.
+func awaitEventTime(t *testing.T, w *Watcher, name string, match func(time.Time, bool) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		last, ok := w.LastEventTime(name)
+		if match(last, ok) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for event time of %s, got: %s, %v", name, last, ok)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcherPauseResume(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	events := make(chan fsnotify.Event, 100)
+	w := newTestWatcher(t, graveyard, WithEventHandler(func(event fsnotify.Event) {
+		events <- event
+	}))
+
+	w.Pause()
+	a := mustRecordBirth(t, graveyard, "a")
+	if err := a.Heartbeat(); err != nil {
+		t.Fatalf("failed to heartbeat: %v", err)
+	}
+	b := mustRecordBirth(t, graveyard, "b")
+
+	// still tracked
+	awaitCounts(t, w, 2, 0)
+	expectNoEvent(t, events, 100*time.Millisecond)
+
+	w.Resume()
+	// coalesced per file, in the order first seen
+	first := nextEvent(t, events)
+	if first.Name != a.Path() || first.Op&fsnotify.Create == 0 {
+		t.Fatalf("expected combined creates of a, got: %v", first)
+	}
+	if second := nextEvent(t, events); second.Name != b.Path() {
+		t.Fatalf("expected event for b second, got: %v", second)
+	}
+	expectNoEvent(t, events, 100*time.Millisecond)
+
+	// delivered directly, once resumed
+	mustRecordBirth(t, graveyard, "c")
+	nextEvent(t, events)
+}
+
+func TestWatcherPauseBuffer(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	events := make(chan fsnotify.Event, 100)
+	w := newTestWatcher(t, graveyard, WithPauseBuffer(1), WithEventHandler(func(event fsnotify.Event) {
+		events <- event
+	}))
+
+	w.Pause()
+	a := mustRecordBirth(t, graveyard, "a")
+	mustRecordBirth(t, graveyard, "b")
+	// dropped, but still tracked
+	awaitCounts(t, w, 2, 0)
+
+	w.Resume()
+	if event := nextEvent(t, events); event.Name != a.Path() {
+		t.Fatalf("expected buffered event for a, got: %v", event)
+	}
+	expectNoEvent(t, events, 100*time.Millisecond)
+}
@@ -0,0 +1,97 @@
+package tombstone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TombstoneHandler is called with each tombstone change. The tombstone is
+// nil if the event is EventRemove.
+type TombstoneHandler func(event EventType, name string, ts *Tombstone)
+
+// readingHandler returns an EventHandler that reads each changed tombstone
+// and calls the handler. Hidden and sidecar files are ignored. Tombstones
+// removed before they can be read (ex: by a reaper) are not errors, because
+// the remove event follows.
+func readingHandler(graveyard string, config options, handler TombstoneHandler) EventHandler {
+	return func(event fsnotify.Event) {
+		name := filepath.Base(event.Name)
+		if isHidden(name) || isSidecar(name) {
+			return
+		}
+		if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+			handler(EventRemove, name, nil)
+			return
+		}
+		if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+			return
+		}
+		ts, err := Read(graveyard, name, withOptions(config))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Error: %v\n", err)
+			}
+			return
+		}
+		handler(EventWrite, name, ts)
+	}
+}
+
+// ReapPolicy configures the reaping done by WatchAndReap.
+type ReapPolicy struct {
+	// Interval is how often to reap.
+	Interval time.Duration
+	// MinIdle skips tombstones modified more recently than this, so that
+	// tombstones still being written (ex: dependents reacting to a death)
+	// aren't removed from under their readers.
+	MinIdle time.Duration
+	// Reap returns true if the dead tombstone should be removed.
+	// Alive tombstones are never removed.
+	Reap func(*Tombstone) bool
+}
+
+// WatchAndReap watches a graveyard, calling the handler with each change,
+// and periodically removes the dead tombstones that match the policy, until
+// the context is done. Reaping happens on the same goroutine as the handler
+// (unless WithConcurrency is used), so the handler never races with the
+// reaper, and receives an EventRemove for each reaped tombstone.
+// WithTick is replaced by the reap interval.
+func WatchAndReap(ctx context.Context, graveyard string, policy ReapPolicy, handler TombstoneHandler, opts ...Option) error {
+	if policy.Interval <= 0 {
+		return fmt.Errorf("invalid reap interval: must be positive: %s", policy.Interval)
+	}
+	if policy.Reap == nil {
+		return errors.New("missing reap predicate")
+	}
+	config := newOptions(opts)
+
+	reap := func(now time.Time) {
+		removed, err := ReapWhere(graveyard, func(ts *Tombstone) bool {
+			info, err := os.Stat(ts.Path())
+			if err != nil || now.Sub(info.ModTime()) < policy.MinIdle {
+				return false
+			}
+			return policy.Reap(ts)
+		}, withOptions(config), WithReapAlive(false))
+		if err != nil {
+			log.Printf("Error: failed to reap graveyard: %v\n", err)
+		}
+		if len(removed) > 0 {
+			log.Printf("Reaped %d tombstone(s): %v\n", len(removed), removed)
+		}
+	}
+
+	err := Watch(ctx, graveyard, readingHandler(graveyard, config, handler),
+		withOptions(config), WithTick(policy.Interval, reap))
+	if err != nil {
+		return fmt.Errorf("failed to watch graveyard: %v", err)
+	}
+	return nil
+}
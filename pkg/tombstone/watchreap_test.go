@@ -0,0 +1,97 @@
+package tombstone
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// handledEvent is a change passed to a TombstoneHandler.
+type handledEvent struct {
+	event EventType
+	name  string
+}
+
+// watchAndReap runs WatchAndReap until the test ends and returns the changes
+// handled.
+func watchAndReap(t *testing.T, graveyard string, policy ReapPolicy) <-chan handledEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	events := make(chan handledEvent, 100)
+	err := WatchAndReap(ctx, graveyard, policy, func(event EventType, name string, ts *Tombstone) {
+		events <- handledEvent{event: event, name: name}
+	})
+	if err != nil {
+		t.Fatalf("failed to watch and reap: %v", err)
+	}
+	return events
+}
+
+// awaitRemoveEvent waits for the handler to receive a remove event.
+func awaitRemoveEvent(t *testing.T, events <-chan handledEvent) string {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.event == EventRemove {
+				return e.name
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for remove event")
+			return ""
+		}
+	}
+}
+
+func succeeded(ts *Tombstone) bool {
+	return ts.Succeeded(nil)
+}
+
+func TestWatchAndReap(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	mustRecordBirth(t, graveyard, "alive")
+	mustRecordDeath(t, graveyard, "failed", 1)
+
+	events := watchAndReap(t, graveyard, ReapPolicy{Interval: 20 * time.Millisecond, Reap: succeeded})
+	mustRecordDeath(t, graveyard, "ok", 0)
+
+	if name := awaitRemoveEvent(t, events); name != "ok" {
+		t.Fatalf("expected ok to be reaped, got: %s", name)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := remainingNames(t, graveyard); !reflect.DeepEqual(got, []string{"alive", "failed"}) {
+		t.Fatalf("expected [alive failed] remaining, got: %v", got)
+	}
+}
+
+func TestWatchAndReapMinIdle(t *testing.T) {
+	graveyard := tempGraveyard(t)
+	ok := mustRecordDeath(t, graveyard, "ok", 0)
+
+	start := time.Now()
+	events := watchAndReap(t, graveyard, ReapPolicy{Interval: 20 * time.Millisecond, MinIdle: 300 * time.Millisecond, Reap: succeeded})
+
+	awaitRemoveEvent(t, events)
+	if _, err := os.Stat(ok.Path()); !os.IsNotExist(err) {
+		t.Fatalf("expected ok to be reaped, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Fatalf("expected reaping to wait until idle, reaped after: %s", elapsed)
+	}
+}
+
+func TestWatchAndReapInvalidPolicy(t *testing.T) {
+	handler := func(EventType, string, *Tombstone) {}
+	graveyard := tempGraveyard(t)
+	if err := WatchAndReap(context.Background(), graveyard, ReapPolicy{Reap: succeeded}, handler); err == nil {
+		t.Fatalf("expected missing interval to fail")
+	}
+	if err := WatchAndReap(context.Background(), graveyard, ReapPolicy{Interval: time.Second}, handler); err == nil {
+		t.Fatalf("expected missing predicate to fail")
+	}
+}